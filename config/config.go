@@ -0,0 +1,504 @@
+// Package config loads struct from defaults, files, env and flags.
+//
+// Precedence from low to high: default tag < files (in order) < env < flags.
+//
+//	type Conf struct {
+//	    Port     int           `config:"port" default:"8080"`
+//	    DSN      string        `config:"dsn" required:"true" secret:"true"`
+//	    Timeout  time.Duration `config:"timeout" default:"3s" validate:"range(1)"`
+//	    Redis    struct {
+//	        DSN string `config:"dsn"` // key redis.dsn, env redis_dsn, flag -redis.dsn
+//	    } `config:"redis"`
+//	}
+//
+//	err := config.Load(&conf, "conf.yaml")
+//
+// Tags:
+//
+//	config   key name, default is snake case of field name, "-" to skip
+//	env      env name, default is key with "." replaced by "_"
+//	default  default value
+//	required field must not be zero
+//	secret   value is redacted in Dump
+//	validate constraint of validator package, e.g. range(1|10), in(a|b)
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	"github.com/arcplus/go-lib/json"
+	"github.com/arcplus/go-lib/validator"
+)
+
+// Loader is config loader.
+type Loader struct {
+	Files     []string // json, yaml, yml, toml, format is detected by ext
+	EnvPrefix string   // prefix of env name
+	Args      []string // flags, default is os.Args[1:]
+	NoEnv     bool     // skip env
+	NoFlag    bool     // skip flags
+	Lenient   bool     // skip bad files and values, constraints are not checked
+}
+
+// Load is helper func to load v from files, env and flags.
+// v must be a pointer to struct.
+func Load(v interface{}, files ...string) error {
+	l := &Loader{
+		Files: files,
+	}
+	return l.Load(v)
+}
+
+// field is leaf field of struct
+type field struct {
+	key      string // a.b
+	env      string // a_b
+	path     string // A.B, for validator
+	def      string
+	hasDef   bool
+	required bool
+	secret   bool
+	validate string
+	rv       reflect.Value
+}
+
+// Load loads v, v must be a pointer to struct.
+func (l *Loader) Load(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config: v must be a pointer to struct")
+	}
+
+	// values are written under lock, so Dump and Effective won't see partial ones
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	fields := collect(rv.Elem(), "", "", l.EnvPrefix)
+
+	// defaults
+	for _, f := range fields {
+		if f.hasDef {
+			if err := setValue(f.rv, f.def); err != nil && !l.Lenient {
+				return fmt.Errorf("config: default of %q err: %s", f.key, err)
+			}
+		}
+	}
+
+	// files
+	for _, name := range l.Files {
+		values, err := readFile(name)
+		if err != nil {
+			if l.Lenient {
+				continue
+			}
+			return fmt.Errorf("config: read %q err: %s", name, err)
+		}
+
+		for _, f := range fields {
+			if val, ok := values[f.key]; ok {
+				if err := setAny(f.rv, val); err != nil && !l.Lenient {
+					return fmt.Errorf("config: %q of %q err: %s", f.key, name, err)
+				}
+			}
+		}
+	}
+
+	// env
+	if !l.NoEnv {
+		for _, f := range fields {
+			if val, ok := os.LookupEnv(f.env); ok {
+				if err := setValue(f.rv, val); err != nil && !l.Lenient {
+					return fmt.Errorf("config: env %q err: %s", f.env, err)
+				}
+			}
+		}
+	}
+
+	// flags
+	if !l.NoFlag {
+		args := l.Args
+		if args == nil && len(os.Args) > 1 {
+			args = os.Args[1:]
+		}
+
+		// only registered names are parsed, so positional args are not consumed by
+		// unknown or bool flags
+		names := make(map[string]bool, 2*len(fields))
+		for _, f := range fields {
+			isBool := f.rv.Kind() == reflect.Bool
+			names[f.key] = isBool
+			names[f.env] = isBool
+		}
+
+		flags := parseFlags(args, names)
+		for _, f := range fields {
+			val, ok := flags[f.key]
+			if !ok {
+				val, ok = flags[f.env]
+			}
+			if ok {
+				if err := setValue(f.rv, val); err != nil && !l.Lenient {
+					return fmt.Errorf("config: flag %q err: %s", f.key, err)
+				}
+			}
+		}
+	}
+
+	var constraints []string
+	for _, f := range fields {
+		if f.required {
+			constraints = append(constraints, f.path)
+		}
+		if f.validate != "" {
+			constraints = append(constraints, f.path+":"+f.validate)
+		}
+	}
+
+	if err := validator.Validate(v, constraints...); err != nil && !l.Lenient {
+		return fmt.Errorf("config: %s", err)
+	}
+
+	register(v)
+
+	return nil
+}
+
+// collect returns all leaf fields of struct rv.
+func collect(rv reflect.Value, keyPrefix, pathPrefix, envPrefix string) []*field {
+	rt := rv.Type()
+
+	var fields []*field
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+
+		key := sf.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = snakecase(sf.Name)
+		}
+		key = keyPrefix + key
+
+		fv := rv.Field(i)
+
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			fields = append(fields, collect(fv, key+".", pathPrefix+sf.Name+".", envPrefix)...)
+			continue
+		}
+
+		f := &field{
+			key:      key,
+			env:      sf.Tag.Get("env"),
+			path:     pathPrefix + sf.Name,
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			validate: sf.Tag.Get("validate"),
+			rv:       fv,
+		}
+
+		f.def, f.hasDef = sf.Tag.Lookup("default")
+
+		if f.env == "" {
+			f.env = envPrefix + strings.Replace(key, ".", "_", -1)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// parseFlags parse -k=v, --k=v, -k v and bool flag -k of names, names maps flag name to
+// whether it's bool. Unknown flags are skipped, they and bool flags don't take next arg.
+func parseFlags(args []string, names map[string]bool) map[string]string {
+	flags := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+
+		if j := strings.Index(name, "="); j != -1 {
+			if _, ok := names[name[:j]]; ok {
+				flags[name[:j]] = name[j+1:]
+			}
+			continue
+		}
+
+		isBool, ok := names[name]
+		if !ok {
+			continue
+		}
+
+		if !isBool && i+1 < len(args) && (len(args[i+1]) == 0 || args[i+1][0] != '-') {
+			flags[name] = args[i+1]
+			i++
+			continue
+		}
+
+		flags[name] = "true"
+	}
+
+	return flags
+}
+
+// Lookup returns value of name from flags first, then env.
+func Lookup(name string) (string, bool) {
+	if len(os.Args) > 1 {
+		if v, ok := parseFlags(os.Args[1:], map[string]bool{name: false})[name]; ok {
+			return v, true
+		}
+	}
+	return os.LookupEnv(name)
+}
+
+// readFile reads file into flatten map, nested keys are joined by ".".
+func readFile(name string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	case ".yaml", ".yml":
+		var ym map[interface{}]interface{}
+		err = yaml.Unmarshal(data, &ym)
+		m = normalize(ym).(map[string]interface{})
+	case ".toml":
+		err = toml.Unmarshal(data, &m)
+	default:
+		return nil, errors.New("unknown config format")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	flatten(values, "", m)
+	return values, nil
+}
+
+// normalize converts yaml map[interface{}]interface{} to map[string]interface{}.
+func normalize(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, v := range vv {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		for i := range vv {
+			vv[i] = normalize(vv[i])
+		}
+		return vv
+	default:
+		return v
+	}
+}
+
+func flatten(values map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		if vm, ok := v.(map[string]interface{}); ok {
+			flatten(values, prefix+k+".", vm)
+			continue
+		}
+		values[prefix+k] = v
+	}
+}
+
+// setAny set rv from value decoded from file.
+func setAny(rv reflect.Value, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	switch vv := v.(type) {
+	case []interface{}:
+		if rv.Kind() != reflect.Slice {
+			return errors.New("list for " + rv.Kind().String())
+		}
+		sv := reflect.MakeSlice(rv.Type(), len(vv), len(vv))
+		for i := range vv {
+			if err := setValue(sv.Index(i), fmt.Sprint(vv[i])); err != nil {
+				return err
+			}
+		}
+		rv.Set(sv)
+		return nil
+	case float64:
+		// json number
+		return setValue(rv, strconv.FormatFloat(vv, 'f', -1, 64))
+	default:
+		return setValue(rv, fmt.Sprint(v))
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue set rv from string, slice is separated by comma.
+func setValue(rv reflect.Value, s string) error {
+	if rv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		if s == "" {
+			rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		sv := reflect.MakeSlice(rv.Type(), len(parts), len(parts))
+		for i := range parts {
+			if err := setValue(sv.Index(i), strings.TrimSpace(parts[i])); err != nil {
+				return err
+			}
+		}
+		rv.Set(sv)
+	default:
+		return errors.New(rv.Kind().String() + " not support now")
+	}
+	return nil
+}
+
+const redacted = "******"
+
+// Dump returns effective config of v loaded by Load, keyed by config key.
+// secret fields are redacted.
+func Dump(v interface{}) map[string]interface{} {
+	loaded.RLock()
+	defer loaded.RUnlock()
+
+	return dump(v)
+}
+
+// dump returns config of v, loaded should be locked.
+func dump(v interface{}) map[string]interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := collect(rv.Elem(), "", "", "")
+
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if f.secret && !f.rv.IsZero() {
+			m[f.key] = redacted
+			continue
+		}
+		m[f.key] = f.rv.Interface()
+	}
+	return m
+}
+
+// loaded holds loaded structs, lock is held by Load while writing values.
+var loaded = struct {
+	sync.RWMutex
+	items map[string]interface{}
+}{
+	items: make(map[string]interface{}),
+}
+
+// register adds v to loaded, loaded should be locked.
+func register(v interface{}) {
+	name := reflect.TypeOf(v).Elem().String()
+	loaded.items[name] = v
+}
+
+// Effective returns effective config of all loaded struct, keyed by type name.
+func Effective() map[string]map[string]interface{} {
+	loaded.RLock()
+	defer loaded.RUnlock()
+
+	m := make(map[string]map[string]interface{}, len(loaded.items))
+	for name, v := range loaded.items {
+		m[name] = dump(v)
+	}
+	return m
+}
+
+// snakecase converts AbcXyz to abc_xyz, DSN to dsn.
+func snakecase(s string) string {
+	b := make([]byte, 0, len(s)+4)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			// new word starts at upper after lower, or upper before lower
+			if i > 0 && (isLower(s[i-1]) || (i+1 < len(s) && isLower(s[i+1]) && isUpper(s[i-1]))) {
+				b = append(b, '_')
+			}
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
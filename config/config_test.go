@@ -0,0 +1,131 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testConf struct {
+	Name    string        `config:"name" default:"app"`
+	Port    int           `config:"port" default:"8080" validate:"range(1|65536)"`
+	Debug   bool          `config:"debug"`
+	Timeout time.Duration `config:"timeout" default:"1s"`
+	Tags    []string      `config:"tags"`
+	DSN     string        `config:"dsn" required:"true" secret:"true"`
+	Redis   struct {
+		DSN string `config:"dsn"`
+	} `config:"redis"`
+	Skip string `config:"-"`
+}
+
+func TestSnakecase(t *testing.T) {
+	cases := map[string]string{
+		"Name":      "name",
+		"DSN":       "dsn",
+		"LogLevel":  "log_level",
+		"HTTPPort":  "http_port",
+		"LogRdsDSN": "log_rds_dsn",
+	}
+	for in, want := range cases {
+		if got := snakecase(in); got != want {
+			t.Fatalf("snakecase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := filepath.Join(dir, "conf.yaml")
+	ioutil.WriteFile(yamlFile, []byte("name: from-yaml\nport: 9000\ntags: [a, b]\nredis:\n  dsn: redis://yaml\n"), 0644)
+
+	jsonFile := filepath.Join(dir, "conf.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"port": 9001, "dsn": "json-dsn"}`), 0644)
+
+	os.Setenv("test_timeout", "3s")
+	defer os.Unsetenv("test_timeout")
+
+	conf := testConf{}
+	l := &Loader{
+		Files:     []string{yamlFile, jsonFile},
+		EnvPrefix: "test_",
+		Args:      []string{"-v", "in.txt", "-debug", "out.txt", "--name=from-flag", "-redis.dsn", "redis://flag"},
+	}
+
+	if err := l.Load(&conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Name != "from-flag" || conf.Port != 9001 || !conf.Debug || conf.Timeout != 3*time.Second ||
+		len(conf.Tags) != 2 || conf.DSN != "json-dsn" || conf.Redis.DSN != "redis://flag" {
+		t.Fatalf("unexpected conf: %+v", conf)
+	}
+
+	d := Dump(&conf)
+	if d["dsn"] != redacted {
+		t.Fatalf("dsn should be redacted, got %v", d["dsn"])
+	}
+	t.Log(d)
+	t.Log(Effective())
+}
+
+func TestLoadRequired(t *testing.T) {
+	conf := testConf{}
+	l := &Loader{
+		NoEnv:  true,
+		NoFlag: true,
+	}
+
+	if err := l.Load(&conf); err == nil {
+		t.Fatal("dsn is required")
+	} else {
+		t.Log(err)
+	}
+
+	conf.DSN = "x"
+	l.Args = []string{"-port=0"}
+	l.NoFlag = false
+	if err := l.Load(&conf); err == nil {
+		t.Fatal("port should be validated")
+	} else {
+		t.Log(err)
+	}
+	// bad values and constraints are skipped
+	conf = testConf{}
+	l.Args = []string{"-port=x", "-name=n"}
+	l.Lenient = true
+	if err := l.Load(&conf); err != nil || conf.Name != "n" || conf.Port != 8080 {
+		t.Fatalf("unexpected lenient load: %+v %v", conf, err)
+	}
+}
+
+func TestEffectiveReload(t *testing.T) {
+	conf := testConf{DSN: "dsn"}
+	l := &Loader{NoEnv: true, Args: []string{"-dsn", "dsn"}}
+	if err := l.Load(&conf); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			Effective()
+			Dump(&conf)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if err := l.Load(&conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
@@ -0,0 +1,257 @@
+package errs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/arcplus/go-lib/json"
+)
+
+// DefaultLocale is used if locale is not specified or has no catalog.
+var DefaultLocale = "en"
+
+// catalogs holds alert templates by locale and key, key is message ID or code.
+var catalogs = struct {
+	sync.RWMutex
+	m map[string]map[string]*template.Template
+}{
+	m: map[string]map[string]*template.Template{},
+}
+
+// AddCatalog adds alert templates of locale, key is message ID (alert of error) or code,
+// template is text/template with alert args, e.g. "user {{.id}} not found".
+func AddCatalog(locale string, msgs map[string]string) error {
+	ts := make(map[string]*template.Template, len(msgs))
+	for k, v := range msgs {
+		t, err := template.New(k).Parse(v)
+		if err != nil {
+			return Annotate(err, "catalog %s key %s: %s", locale, k, err)
+		}
+		ts[k] = t
+	}
+
+	locale = normalizeLocale(locale)
+
+	catalogs.Lock()
+	defer catalogs.Unlock()
+
+	if catalogs.m[locale] == nil {
+		catalogs.m[locale] = ts
+		return nil
+	}
+
+	for k, t := range ts {
+		catalogs.m[locale][k] = t
+	}
+	return nil
+}
+
+// LoadCatalog loads alert templates of locale from JSON or YAML file by extension.
+//
+// For example, zh-CN.yaml:
+//
+//	1404: 资源不存在
+//	user_not_found: 用户 {{.id}} 不存在
+func LoadCatalog(locale, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Trace(err)
+	}
+
+	msgs := map[string]string{}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &msgs)
+	default:
+		err = json.Unmarshal(data, &msgs)
+	}
+	if err != nil {
+		return Annotate(err, "catalog %s: %s", filename, err)
+	}
+
+	return AddCatalog(locale, msgs)
+}
+
+// Locales returns locales having catalog.
+func Locales() []string {
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	locales := make([]string, 0, len(catalogs.m))
+	for l := range catalogs.m {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// MatchLocale returns the best locale having catalog of Accept-Language value, e.g.
+// "zh-CN,zh;q=0.9,en;q=0.8", base language is tried if region doesn't match.
+// It returns "" if none matches.
+func MatchLocale(acceptLanguage string) string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+
+	var tags []tag
+	for _, s := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(s, ";")
+		t := tag{locale: normalizeLocale(parts[0]), q: 1}
+		if t.locale == "" || t.locale == "*" {
+			continue
+		}
+		for _, p := range parts[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				t.q, _ = strconv.ParseFloat(p[2:], 64)
+			}
+		}
+		if t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	for _, t := range tags {
+		if _, ok := catalogs.m[t.locale]; ok {
+			return t.locale
+		}
+		if i := strings.IndexByte(t.locale, '-'); i > 0 {
+			if _, ok := catalogs.m[t.locale[:i]]; ok {
+				return t.locale[:i]
+			}
+		}
+	}
+
+	return ""
+}
+
+type localeKey struct{}
+
+// WithLocale returns copy of ctx with locale, it's used by Localize.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, normalizeLocale(locale))
+}
+
+// LocaleFromContext returns locale of ctx, "" if not set.
+func LocaleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// Localize returns copy of err with locale of ctx, so MarshalJSON and GRPCStatus emit
+// alert of the locale. err is converted to *Error if it's not. Location and stack of
+// err are kept, Localize call is not recorded.
+func Localize(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var er *Error
+	if e, ok := err.(*Error); ok {
+		en := *e // shallow copy, prev is not linked
+		er = &en
+	} else {
+		er = toError(err)
+	}
+
+	er.locale = LocaleFromContext(ctx)
+
+	return er
+}
+
+// WithAlertArgs sets args of alert template by key value pairs.
+//
+// For example:
+//
+//	err := errs.NewWithAlert(errs.CodeNotFound, "user_not_found", "user %d not found", id)
+//	return errs.WithAlertArgs(err, "id", id)
+func WithAlertArgs(e error, kv ...interface{}) error {
+	er := toError(e)
+	if er == nil {
+		return nil
+	}
+
+	args := make(map[string]interface{}, len(er.alertArgs)+len(kv)/2)
+	for k, v := range er.alertArgs {
+		args[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		args[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+
+	er.alertArgs = args
+	er.setLocation(1)
+
+	return er
+}
+
+// Locale returns locale set by Localize.
+func (e *Error) Locale() string {
+	return e.locale
+}
+
+// LocalizedAlert returns alert of locale, template is keyed by alert (as message ID),
+// or by code if alert is empty. DefaultLocale is used if locale has no such template,
+// alert is returned as is if no template found.
+func (e *Error) LocalizedAlert(locale string) string {
+	key := e.alert
+	if key == "" {
+		key = strconv.FormatUint(uint64(e.Code()), 10)
+	}
+
+	locales := []string{normalizeLocale(locale)}
+	if i := strings.IndexByte(locales[0], '-'); i > 0 {
+		locales = append(locales, locales[0][:i])
+	}
+	locales = append(locales, normalizeLocale(DefaultLocale))
+
+	catalogs.RLock()
+	defer catalogs.RUnlock()
+
+	for _, l := range locales {
+		t := catalogs.m[l][key]
+		if t == nil {
+			continue
+		}
+
+		var b strings.Builder
+		if err := t.Execute(&b, e.alertArgs); err != nil {
+			continue
+		}
+		return b.String()
+	}
+
+	return e.alert
+}
+
+// normalizeLocale trims and lowers language, uppers region, e.g. zh_cn to zh-CN.
+func normalizeLocale(locale string) string {
+	locale = strings.Replace(strings.TrimSpace(locale), "_", "-", -1)
+
+	i := strings.IndexByte(locale, '-')
+	if i < 0 {
+		return strings.ToLower(locale)
+	}
+
+	return strings.ToLower(locale[:i]) + "-" + strings.ToUpper(locale[i+1:])
+}
@@ -0,0 +1,144 @@
+package errs
+
+import (
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// CodeMapping is gRPC code and HTTP status of an app code.
+type CodeMapping struct {
+	GRPC codes.Code
+	HTTP int
+}
+
+var registry = struct {
+	sync.RWMutex
+	codes    map[uint32]CodeMapping
+	fromGRPC map[codes.Code]uint32
+	fromHTTP map[int]uint32
+}{
+	codes:    map[uint32]CodeMapping{},
+	fromGRPC: map[codes.Code]uint32{},
+	fromHTTP: map[int]uint32{},
+}
+
+func init() {
+	RegisterCode(CodeOK, codes.OK, http.StatusOK)
+	RegisterCode(CodeInternal, codes.Internal, http.StatusInternalServerError)
+	RegisterCode(CodeBadRequest, codes.InvalidArgument, http.StatusBadRequest)
+	RegisterCode(CodeUnAuth, codes.Unauthenticated, http.StatusUnauthorized)
+	RegisterCode(CodeForbidden, codes.PermissionDenied, http.StatusForbidden)
+	RegisterCode(CodeNotFound, codes.NotFound, http.StatusNotFound)
+	RegisterCode(CodeNotAllowed, codes.FailedPrecondition, http.StatusMethodNotAllowed)
+	RegisterCode(CodeConflict, codes.AlreadyExists, http.StatusConflict)
+}
+
+// RegisterCode registers or overrides gRPC code and HTTP status of app code.
+// The first code registered for a gRPC code or HTTP status is used to convert back,
+// so custom codes don't shadow built-in ones.
+//
+// For example:
+//
+//	const CodeQuotaExceeded uint32 = 20001
+//	errs.RegisterCode(CodeQuotaExceeded, codes.ResourceExhausted, http.StatusTooManyRequests)
+func RegisterCode(code uint32, grpcCode codes.Code, httpStatus int) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.codes[code] = CodeMapping{GRPC: grpcCode, HTTP: httpStatus}
+
+	if _, ok := registry.fromGRPC[grpcCode]; !ok {
+		registry.fromGRPC[grpcCode] = code
+	}
+	if _, ok := registry.fromHTTP[httpStatus]; !ok {
+		registry.fromHTTP[httpStatus] = code
+	}
+}
+
+// Mapping returns gRPC code and HTTP status of app code. Unregistered code like 1xxx is
+// treated as HTTP-flavoured if xxx is 4xx or 5xx, others are internal error.
+func Mapping(code uint32) CodeMapping {
+	registry.RLock()
+	m, ok := registry.codes[code]
+	registry.RUnlock()
+
+	if ok {
+		return m
+	}
+
+	if code >= 1400 && code < 1600 {
+		status := int(code - 1000)
+		return CodeMapping{GRPC: grpcCodeOfHTTP(status), HTTP: status}
+	}
+
+	return CodeMapping{GRPC: codes.Internal, HTTP: http.StatusInternalServerError}
+}
+
+// GRPCCode returns gRPC code of app code.
+func GRPCCode(code uint32) codes.Code {
+	return Mapping(code).GRPC
+}
+
+// HTTPStatus returns HTTP status of app code.
+func HTTPStatus(code uint32) int {
+	return Mapping(code).HTTP
+}
+
+// FromGRPCCode returns app code of gRPC code, CodeInternal if not registered.
+func FromGRPCCode(c codes.Code) uint32 {
+	registry.RLock()
+	code, ok := registry.fromGRPC[c]
+	registry.RUnlock()
+
+	if ok {
+		return code
+	}
+
+	return CodeInternal
+}
+
+// FromHTTPStatus returns app code of HTTP status, unregistered 4xx is CodeBadRequest,
+// others are CodeInternal.
+func FromHTTPStatus(status int) uint32 {
+	registry.RLock()
+	code, ok := registry.fromHTTP[status]
+	registry.RUnlock()
+
+	switch {
+	case ok:
+		return code
+	case status >= 400 && status < 500:
+		return CodeBadRequest
+	default:
+		return CodeInternal
+	}
+}
+
+// grpcCodeOfHTTP follows https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func grpcCodeOfHTTP(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	}
+
+	if status >= 400 && status < 500 {
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
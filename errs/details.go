@@ -0,0 +1,207 @@
+package errs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is domain of google.rpc.ErrorInfo detail.
+const ErrorDomain = "github.com/arcplus/go-lib/errs"
+
+// ProdMode drops debug stack from gRPC status details, it's true if env mode is
+// prod or production, micro.New sets it by resolved mode.
+var ProdMode = os.Getenv("mode") == "prod" || os.Getenv("mode") == "production"
+
+// FieldViolation describes a bad field of request.
+type FieldViolation struct {
+	Field   string            `json:"field"`            // path of field, e.g. "user.emails[0]"
+	Rule    string            `json:"rule,omitempty"`   // failed rule, e.g. "required", "max"
+	Message string            `json:"message"`          // human readable description
+	Params  map[string]string `json:"params,omitempty"` // params of rule, e.g. {"max": "10"}
+}
+
+// Violations returns field violations.
+func (e *Error) Violations() []FieldViolation {
+	return e.violations
+}
+
+// RetryDelay returns how long clients should wait before retrying, 0 means unknown.
+func (e *Error) RetryDelay() time.Duration {
+	return e.retry
+}
+
+// WithViolations adds field violations to err.
+//
+// For example:
+//
+//	return errs.WithViolations(errs.New(errs.CodeBadRequest, "invalid user"),
+//		errs.FieldViolation{Field: "name", Rule: "required", Message: "name is required"})
+func WithViolations(e error, vs ...FieldViolation) error {
+	er := toError(e)
+	if er == nil {
+		return nil
+	}
+
+	er.violations = append(er.violations[:len(er.violations):len(er.violations)], vs...)
+	er.setLocation(1)
+
+	return er
+}
+
+// WithRetryDelay sets retry delay of err.
+func WithRetryDelay(e error, d time.Duration) error {
+	er := toError(e)
+	if er == nil {
+		return nil
+	}
+
+	er.retry = d
+	er.setLocation(1)
+
+	return er
+}
+
+// GRPCStatus implements grpc status interface, code, localized alert, violations, retry delay
+// and stack (if not ProdMode) are encoded as google.rpc.Status details.
+func (e *Error) GRPCStatus() *status.Status {
+	code := e.Code()
+
+	grpcCode := e.grpcCode
+	if grpcCode == codes.OK {
+		grpcCode = GRPCCode(code)
+	}
+	s := status.New(grpcCode, e.Message())
+
+	s = withDetail(s, &errdetails.ErrorInfo{
+		Reason:   strconv.FormatUint(uint64(code), 10),
+		Domain:   ErrorDomain,
+		Metadata: violationParams(e.violations),
+	})
+
+	if alert := e.LocalizedAlert(e.locale); alert != "" {
+		s = withDetail(s, &errdetails.LocalizedMessage{Locale: e.locale, Message: alert})
+	}
+
+	if len(e.violations) != 0 {
+		br := &errdetails.BadRequest{}
+		for _, v := range e.violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+				Reason:      v.Rule,
+			})
+		}
+		s = withDetail(s, br)
+	}
+
+	if e.retry > 0 {
+		s = withDetail(s, &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(e.retry)})
+	}
+
+	if !ProdMode {
+		s = withDetail(s, &errdetails.DebugInfo{StackEntries: stack(e)})
+	}
+
+	return s
+}
+
+// FromGRPCStatus rebuilds *Error from s, code is mapped from gRPC code if s has no ErrorInfo.
+// gRPC code of s is kept, so GRPCStatus of it returns the same code, e.g. codes.Unavailable.
+func FromGRPCStatus(s *status.Status) *Error {
+	if s == nil || s.Code() == codes.OK {
+		return nil
+	}
+
+	er := &Error{
+		code:     FromGRPCCode(s.Code()),
+		msg:      s.Message(),
+		grpcCode: s.Code(),
+	}
+
+	var params map[string]string
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if d.Domain != ErrorDomain {
+				continue
+			}
+			if code, err := strconv.ParseUint(d.Reason, 10, 32); err == nil {
+				er.code = uint32(code)
+			}
+			params = d.Metadata
+		case *errdetails.LocalizedMessage:
+			er.alert = d.Message
+			er.locale = d.Locale
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				er.violations = append(er.violations, FieldViolation{
+					Field:   v.Field,
+					Rule:    v.Reason,
+					Message: v.Description,
+				})
+			}
+		case *errdetails.RetryInfo:
+			er.retry, _ = ptypes.Duration(d.RetryDelay)
+		case *errdetails.DebugInfo:
+			er.remote = d.StackEntries
+		}
+	}
+
+	setViolationParams(er.violations, params)
+	er.setLocation(1)
+
+	return er
+}
+
+func withDetail(s *status.Status, d proto.Message) *status.Status {
+	if sd, err := s.WithDetails(d); err == nil {
+		return sd
+	}
+	return s
+}
+
+// violationParams flattens params of violations as "violations.<i>.<name>" for ErrorInfo metadata.
+func violationParams(vs []FieldViolation) map[string]string {
+	var m map[string]string
+	for i, v := range vs {
+		for k, p := range v.Params {
+			if m == nil {
+				m = map[string]string{}
+			}
+			m["violations."+strconv.Itoa(i)+"."+k] = p
+		}
+	}
+	return m
+}
+
+func setViolationParams(vs []FieldViolation, m map[string]string) {
+	for k, p := range m {
+		if !strings.HasPrefix(k, "violations.") {
+			continue
+		}
+
+		k = k[len("violations."):]
+		dot := strings.IndexByte(k, '.')
+		if dot < 0 {
+			continue
+		}
+
+		i, err := strconv.Atoi(k[:dot])
+		if err != nil || i < 0 || i >= len(vs) {
+			continue
+		}
+
+		if vs[i].Params == nil {
+			vs[i].Params = map[string]string{}
+		}
+		vs[i].Params[k[dot+1:]] = p
+	}
+}
@@ -3,6 +3,9 @@ package errs
 import (
 	"fmt"
 	"runtime"
+	"time"
+
+	"google.golang.org/grpc/codes"
 
 	"github.com/arcplus/go-lib/json"
 )
@@ -24,7 +27,15 @@ type Error struct {
 	code  uint32        // code
 	msg   string        // msg
 	args  []interface{} // fmt
-	alert string        // alert info
+	alert string        // alert info, or message ID of catalog
+
+	alertArgs map[string]interface{} // args of alert template
+	locale    string                 // locale of alert
+
+	violations []FieldViolation
+	retry      time.Duration
+	remote     []string   // stack of peer, restored from gRPC status
+	grpcCode   codes.Code // gRPC code of peer, restored from gRPC status
 
 	// previous holds the previous error in the error stack, if any.
 	prev error
@@ -51,7 +62,7 @@ func (e *Error) Message() string {
 	return e.msg
 }
 
-// Alert is used for err hint
+// Alert is used for err hint, see LocalizedAlert for alert of locale.
 func (e *Error) Alert() string {
 	return e.alert
 }
@@ -74,17 +85,19 @@ func (e *Error) Location() (file string, line int) {
 }
 
 type errJSON struct {
-	Code    uint32 `json:"code"`
-	Message string `json:"message"`
-	Alert   string `json:"alert"`
+	Code       uint32           `json:"code"`
+	Message    string           `json:"message"`
+	Alert      string           `json:"alert"`
+	Violations []FieldViolation `json:"violations,omitempty"`
 }
 
-// MarshalJSON implements json.Marshaler interface.
+// MarshalJSON implements json.Marshaler interface, alert is localized by locale set by Localize.
 func (e *Error) MarshalJSON() ([]byte, error) {
 	return json.Marshal(errJSON{
-		Code:    e.Code(),
-		Message: e.Message(),
-		Alert:   e.alert,
+		Code:       e.Code(),
+		Message:    e.Message(),
+		Alert:      e.LocalizedAlert(e.locale),
+		Violations: e.violations,
 	})
 }
 
@@ -99,6 +112,7 @@ func (e *Error) UnmarshalJSON(data []byte) error {
 	e.code = info.Code
 	e.msg = info.Message
 	e.alert = info.Alert
+	e.violations = info.Violations
 	return nil
 }
 
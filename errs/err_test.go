@@ -1,8 +1,18 @@
 package errs
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/arcplus/go-lib/json"
 )
@@ -93,3 +103,223 @@ func TestDeferredAnnotate(t *testing.T) {
 		t.Fatal(e2)
 	}
 }
+
+func TestCodeMapping(t *testing.T) {
+	if GRPCCode(CodeNotFound) != codes.NotFound || HTTPStatus(CodeNotFound) != http.StatusNotFound {
+		t.Fatal("unexpected mapping of CodeNotFound")
+	}
+
+	if FromGRPCCode(codes.NotFound) != CodeNotFound || FromHTTPStatus(http.StatusNotFound) != CodeNotFound {
+		t.Fatal("unexpected reverse mapping of CodeNotFound")
+	}
+
+	// HTTP-flavoured but unregistered
+	if m := Mapping(1429); m.GRPC != codes.ResourceExhausted || m.HTTP != http.StatusTooManyRequests {
+		t.Fatal(m)
+	}
+
+	if m := Mapping(errCodeTest); m.GRPC != codes.Internal || m.HTTP != http.StatusInternalServerError {
+		t.Fatal(m)
+	}
+
+	var codeQuota uint32 = 20001
+	RegisterCode(codeQuota, codes.NotFound, http.StatusTooManyRequests)
+	if GRPCCode(codeQuota) != codes.NotFound || HTTPStatus(codeQuota) != http.StatusTooManyRequests {
+		t.Fatal("custom code should be registered")
+	}
+
+	// built-in one is kept
+	if FromGRPCCode(codes.NotFound) != CodeNotFound {
+		t.Fatal("custom code should not shadow built-in one")
+	}
+	if FromHTTPStatus(http.StatusTooManyRequests) != codeQuota {
+		t.Fatal("custom code should be used for unregistered status")
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	e1 := NewWithAlert(CodeBadRequest, "invalid user", "bad user %d", 1)
+	e1 = WithViolations(e1, FieldViolation{Field: "name", Rule: "max", Message: "name is too long", Params: map[string]string{"max": "10"}})
+	e1 = WithRetryDelay(e1, time.Second)
+
+	s := e1.(*Error).GRPCStatus()
+	if s.Code() != codes.InvalidArgument || s.Message() != "bad user 1" {
+		t.Fatal(s)
+	}
+
+	e2 := FromGRPCStatus(s)
+	if e2.Code() != CodeBadRequest || e2.Message() != "bad user 1" || e2.Alert() != "invalid user" {
+		t.Fatal(e2)
+	}
+	if e2.RetryDelay() != time.Second {
+		t.Fatal(e2.RetryDelay())
+	}
+	if vs := e2.Violations(); len(vs) != 1 || vs[0].Field != "name" || vs[0].Rule != "max" || vs[0].Params["max"] != "10" {
+		t.Fatal(vs)
+	}
+	if !strings.Contains(StackTrace(e2), "err_test.go") {
+		t.Fatal("stack of peer should be kept")
+	}
+
+	// gRPC code without ErrorInfo is kept
+	e4 := FromGRPCStatus(status.New(codes.Unavailable, "db unavailable"))
+	if e4.Code() != CodeInternal || e4.GRPCStatus().Code() != codes.Unavailable {
+		t.Fatal(e4, e4.GRPCStatus().Code())
+	}
+	if s := Trace(e4).(*Error).GRPCStatus(); s.Code() != codes.Unavailable {
+		t.Fatal(s.Code())
+	}
+	if s := Wrap(e4, CodeNotFound).(*Error).GRPCStatus(); s.Code() != codes.NotFound {
+		t.Fatal(s.Code())
+	}
+
+	ProdMode = true
+	defer func() { ProdMode = false }()
+
+	e3 := FromGRPCStatus(e1.(*Error).GRPCStatus())
+	if len(e3.remote) != 0 {
+		t.Fatal("stack should be dropped in prod mode")
+	}
+}
+
+func TestViolations(t *testing.T) {
+	var vs Violations
+	if vs.Err() != nil {
+		t.Fatal("empty violations should be nil")
+	}
+
+	vs.Add("name", "required", "name is required", nil)
+	vs.Add("emails", "max", "too many emails", map[string]string{"max": "10"})
+
+	if !IsCode(vs, CodeBadRequest) || vs.Error() != "[1400]name: name is required; emails: too many emails" {
+		t.Fatal(vs)
+	}
+
+	data, _ := json.Marshal(vs)
+	if string(data) != `[{"field":"name","rule":"required","message":"name is required"},{"field":"emails","rule":"max","message":"too many emails","params":{"max":"10"}}]` {
+		t.Fatal(string(data))
+	}
+
+	e := Trace(vs).(*Error)
+	if len(e.Violations()) != 2 {
+		t.Fatal("violations should be kept", e.Violations())
+	}
+
+	data, _ = json.Marshal(e)
+	t.Log(string(data))
+
+	e2 := &Error{}
+	if err := json.Unmarshal(data, e2); err != nil {
+		t.Fatal(err)
+	}
+	if len(e2.Violations()) != 2 || e2.Violations()[1].Params["max"] != "10" {
+		t.Fatal(e2.Violations())
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	if Join(nil, nil) != nil || Append(nil) != nil || Combine(nil) != nil {
+		t.Fatal("should be nil")
+	}
+
+	if err := Combine(nil, errGo); err != errGo {
+		t.Fatal("single error should be returned unchanged", err)
+	}
+	if _, ok := Combine(errGo, errGo).(*MultiError); !ok {
+		t.Fatal("should be *MultiError")
+	}
+
+	e1 := New(CodeNotFound, "user not found")
+	err := Append(nil, e1, nil)
+	err = Append(err, errGo)
+
+	me, ok := err.(*MultiError)
+	if !ok || len(me.Errors()) != 2 {
+		t.Fatal(err)
+	}
+	if err.Error() != "2 errors: [1404]user not found; go error" {
+		t.Fatal(err.Error())
+	}
+
+	if !IsErr(err, errGo) || !IsErr(Trace(err), e1) || !errors.Is(err, errGo) {
+		t.Fatal("err should contain children")
+	}
+
+	if !IsCode(err, CodeNotFound) || !IsCode(Trace(err), CodeNotFound) || IsCode(err, CodeConflict) {
+		t.Fatal("err should have codes of children")
+	}
+
+	var target *Error
+	if !errors.As(err, &target) || target.Code() != CodeNotFound {
+		t.Fatal("errors.As should find *Error")
+	}
+
+	stack := StackTrace(err)
+	t.Log(stack)
+	if strings.Count(stack, "err_test.go") != 2 {
+		t.Fatal("stack should have locations of err and children")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"user_not_found": "user {{.id}} not found", "1409": "conflict"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "zh-CN.yaml"), []byte("user_not_found: 用户 {{.id}} 不存在\n"), 0644)
+
+	if err := LoadCatalog("en", filepath.Join(dir, "en.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCatalog("zh_cn", filepath.Join(dir, "zh-CN.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := MatchLocale("fr, zh-TW;q=0.8, en;q=0.5"); l != "en" {
+		t.Fatal(l)
+	}
+	if l := MatchLocale("zh-CN,zh;q=0.9"); l != "zh-CN" {
+		t.Fatal(l)
+	}
+
+	e1 := WithAlertArgs(NewWithAlert(CodeNotFound, "user_not_found", "user %d not found", 1), "id", 1)
+
+	if alert := e1.(*Error).LocalizedAlert("zh-CN"); alert != "用户 1 不存在" {
+		t.Fatal(alert)
+	}
+
+	// fallback to DefaultLocale
+	if alert := e1.(*Error).LocalizedAlert("ja"); alert != "user 1 not found" {
+		t.Fatal(alert)
+	}
+
+	ctx := WithLocale(context.Background(), "zh-CN")
+	data, _ := json.Marshal(Localize(ctx, e1))
+	if !strings.Contains(string(data), `"alert":"用户 1 不存在"`) {
+		t.Fatal(string(data))
+	}
+
+	// by code if no alert
+	data, _ = json.Marshal(New(CodeConflict, "dup key"))
+	if !strings.Contains(string(data), `"alert":"conflict"`) {
+		t.Fatal(string(data))
+	}
+
+	// alert without template is kept
+	if alert := NewWithAlert(CodeConflict, "已存在", "dup key").(*Error).LocalizedAlert("en"); alert != "已存在" {
+		t.Fatal(alert)
+	}
+
+	// stack is not changed by Localize
+	if s1, s2 := StackTrace(e1), StackTrace(Localize(ctx, e1)); s1 != s2 {
+		t.Fatalf("stack should be kept, got\n%s\nwant\n%s", s2, s1)
+	}
+
+	e2 := FromGRPCStatus(Localize(ctx, e1).(*Error).GRPCStatus())
+	if e2.Alert() != "用户 1 不存在" || e2.Locale() != "zh-CN" {
+		t.Fatal(e2.Alert(), e2.Locale())
+	}
+}
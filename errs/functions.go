@@ -13,6 +13,12 @@ type Wrapper interface {
 	Unwrap() error
 }
 
+// MultiWrapper is an error implementation
+// wrapping multiple errors, e.g. *MultiError.
+type MultiWrapper interface {
+	Unwrap() []error
+}
+
 // Locationer indicate where error occured.
 type Locationer interface {
 	Location() (string, int)
@@ -33,9 +39,19 @@ func toError(e error) *Error {
 	if er, ok := e.(*Error); ok {
 		en := *er
 		en.prev = er
+		en.remote = nil // kept by prev
 		return &en
 	}
 
+	if vs, ok := e.(Violations); ok {
+		return &Error{
+			code:       vs.Code(),
+			msg:        vs.Message(),
+			violations: vs,
+			prev:       e,
+		}
+	}
+
 	if er, ok := e.(Errorer); ok {
 		return &Error{
 			code: er.Code(),
@@ -74,10 +90,11 @@ func New(code uint32, msg string, args ...interface{}) error {
 }
 
 // NewWithAlert is a drop in replacement for the standard library errors module that records
-// the location that the error is created but with alert msg for show.
+// the location that the error is created but with alert msg for show, alert is also
+// message ID of catalogs, see LocalizedAlert.
 //
 // For example:
-//    return errs.New(404, "用户不存在", "user not found")
+//    return errs.NewWithAlert(errs.CodeNotFound, "user_not_found", "user %d not found", id)
 //
 func NewWithAlert(code uint32, alert string, msg string, args ...interface{}) error {
 	if code == CodeOK {
@@ -129,6 +146,7 @@ func Wrap(e error, code uint32, v ...interface{}) error {
 	}
 
 	er.code = code
+	er.grpcCode = 0
 	if len(v) != 0 {
 		if v0, ok := v[0].(string); ok {
 			er.msg = v0
@@ -224,12 +242,21 @@ func Cause(e error) error {
 	return e
 }
 
-// IsErr reports whether err or any of the errors in its chain is equal to target.
+// IsErr reports whether err or any of the errors in its chain is equal to target,
+// each error of MultiWrapper is checked.
 func IsErr(err, target error) bool {
 	for {
 		if err == target {
 			return true
 		}
+		if m, ok := err.(MultiWrapper); ok {
+			for _, e := range m.Unwrap() {
+				if IsErr(e, target) {
+					return true
+				}
+			}
+			return false
+		}
 		wrapper, ok := err.(Wrapper)
 		if !ok {
 			return false
@@ -241,12 +268,23 @@ func IsErr(err, target error) bool {
 	}
 }
 
+// IsCode reports whether err or any of the errors in its chain has code,
+// each error of MultiWrapper is checked.
 func IsCode(err error, code uint32) bool {
 	for {
 		if err == nil {
 			return false
 		}
 
+		if m, ok := err.(MultiWrapper); ok {
+			for _, e := range m.Unwrap() {
+				if IsCode(e, code) {
+					return true
+				}
+			}
+			return false
+		}
+
 		e, ok := err.(Errorer)
 		if !ok {
 			return false
@@ -285,13 +323,28 @@ func stack(err error) []string {
 		}
 
 		buff = append(buff, err.Error()...)
+		lines = append(lines, string(buff))
+
+		if e, ok := err.(*Error); ok && len(e.remote) != 0 {
+			lines = append(lines, e.remote...)
+		}
+
+		// stack of each error is indented
+		if m, ok := err.(MultiWrapper); ok {
+			for _, e := range m.Unwrap() {
+				for _, l := range stack(e) {
+					lines = append(lines, "\t"+l)
+				}
+			}
+			break
+		}
+
 		if c, ok := err.(Wrapper); ok {
 			err = c.Unwrap()
 		} else {
 			err = nil
 		}
 
-		lines = append(lines, string(buff))
 		if err == nil {
 			break
 		}
@@ -0,0 +1,144 @@
+package errs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MultiError aggregates errors, each one keeps its own code and location.
+type MultiError struct {
+	errs []error
+
+	// file and line hold the source code location where the error was
+	// created.
+	file string
+	line int
+}
+
+// Append appends errs to err, nil ones are skipped. It returns nil if no error,
+// otherwise *MultiError, err is flattened if it's *MultiError.
+//
+// For example:
+//
+//	var err error
+//	for _, f := range files {
+//		err = errs.Append(err, os.Remove(f))
+//	}
+//	return err
+func Append(err error, errs ...error) error {
+	var me *MultiError
+	if m, ok := err.(*MultiError); ok {
+		me = &MultiError{errs: m.errs[:len(m.errs):len(m.errs)]}
+	} else {
+		me = &MultiError{}
+		if err != nil {
+			me.errs = append(me.errs, err)
+		}
+	}
+
+	for _, e := range errs {
+		if e != nil {
+			me.errs = append(me.errs, e)
+		}
+	}
+
+	if len(me.errs) == 0 {
+		return nil
+	}
+
+	me.setLocation(1)
+
+	return me
+}
+
+// Join returns *MultiError of errs, nil if all errs are nil.
+func Join(errs ...error) error {
+	me := &MultiError{}
+	for _, e := range errs {
+		if e != nil {
+			me.errs = append(me.errs, e)
+		}
+	}
+
+	if len(me.errs) == 0 {
+		return nil
+	}
+
+	me.setLocation(1)
+
+	return me
+}
+
+// Combine returns the only non-nil one of errs unchanged, *MultiError if more than one,
+// nil if all errs are nil.
+func Combine(errs ...error) error {
+	me := &MultiError{}
+	for _, e := range errs {
+		if e != nil {
+			me.errs = append(me.errs, e)
+		}
+	}
+
+	switch len(me.errs) {
+	case 0:
+		return nil
+	case 1:
+		return me.errs[0]
+	}
+
+	me.setLocation(1)
+
+	return me
+}
+
+// Errors returns errors aggregated.
+func (m *MultiError) Errors() []error {
+	return m.errs
+}
+
+// Unwrap returns errors aggregated, errors.Is and errors.As check each of them.
+func (m *MultiError) Unwrap() []error {
+	return m.errs
+}
+
+// Code returns code of the first error.
+func (m *MultiError) Code() uint32 {
+	if e, ok := m.errs[0].(Errorer); ok {
+		return e.Code()
+	}
+	return CodeInternal
+}
+
+// Message returns messages of errors joined by "; ".
+func (m *MultiError) Message() string {
+	if len(m.errs) == 1 {
+		return m.errs[0].Error()
+	}
+
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(m.errs)))
+	b.WriteString(" errors: ")
+	for i, e := range m.errs {
+		if i != 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// Error implements error interface.
+func (m *MultiError) Error() string {
+	return m.Message()
+}
+
+// Location returns the location where the error is created.
+func (m *MultiError) Location() (file string, line int) {
+	return m.file, m.line
+}
+
+func (m *MultiError) setLocation(callDepth int) {
+	e := &Error{}
+	e.setLocation(callDepth + 1)
+	m.file, m.line = e.file, e.line
+}
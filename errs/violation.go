@@ -0,0 +1,75 @@
+package errs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violations aggregates field violations of a bad request, it's an Errorer of CodeBadRequest
+// and marshals to JSON as a list.
+//
+// For example:
+//
+//	var vs errs.Violations
+//	if req.Name == "" {
+//		vs.Add("name", "required", "name is required", nil)
+//	}
+//	if len(req.Emails) > 10 {
+//		vs.Add("emails", "max", "too many emails", map[string]string{"max": "10"})
+//	}
+//	return vs.Err()
+type Violations []FieldViolation
+
+// Add appends a violation.
+func (vs *Violations) Add(field, rule, msg string, params map[string]string) {
+	*vs = append(*vs, FieldViolation{
+		Field:   field,
+		Rule:    rule,
+		Message: msg,
+		Params:  params,
+	})
+}
+
+// Code returns CodeBadRequest.
+func (vs Violations) Code() uint32 {
+	return CodeBadRequest
+}
+
+// Message joins messages of all violations.
+func (vs Violations) Message() string {
+	var b strings.Builder
+	for i, v := range vs {
+		if i != 0 {
+			b.WriteString("; ")
+		}
+		if v.Field != "" {
+			b.WriteString(v.Field)
+			b.WriteString(": ")
+		}
+		b.WriteString(v.Message)
+	}
+	return b.String()
+}
+
+// Error implements error interface.
+func (vs Violations) Error() string {
+	return fmt.Sprintf("[%d]%s", vs.Code(), vs.Message())
+}
+
+// Err returns nil if vs is empty, otherwise *Error of CodeBadRequest with violations,
+// the location of Err call is recorded.
+func (vs Violations) Err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+
+	er := &Error{
+		code:       CodeBadRequest,
+		msg:        vs.Message(),
+		violations: vs,
+	}
+
+	er.setLocation(1)
+
+	return er
+}
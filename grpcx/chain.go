@@ -41,6 +41,23 @@ func buildServerStreamInterceptor(c grpc.StreamServerInterceptor, info *grpc.Str
 	}
 }
 
+// ChainUnaryClient build the multi interceptors into one interceptor chain.
+func ChainUnaryClient(interceptors ...grpc.UnaryClientInterceptor) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		chain := invoker
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			chain = buildClientUnaryInterceptor(interceptors[i], chain)
+		}
+		return chain(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func buildClientUnaryInterceptor(c grpc.UnaryClientInterceptor, invoker grpc.UnaryInvoker) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return c(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
+
 // WithUnaryServerChain is a grpc.Server config option that accepts multiple unary interceptors.
 func WithUnaryServerChain(interceptors ...grpc.UnaryServerInterceptor) grpc.ServerOption {
 	return grpc.UnaryInterceptor(ChainUnaryServer(interceptors...))
@@ -50,3 +67,8 @@ func WithUnaryServerChain(interceptors ...grpc.UnaryServerInterceptor) grpc.Serv
 func WithStreamServerChain(interceptors ...grpc.StreamServerInterceptor) grpc.ServerOption {
 	return grpc.StreamInterceptor(ChainStreamServer(interceptors...))
 }
+
+// WithUnaryClientChain is a grpc.Dial option that accepts multiple unary interceptors.
+func WithUnaryClientChain(interceptors ...grpc.UnaryClientInterceptor) grpc.DialOption {
+	return grpc.WithUnaryInterceptor(ChainUnaryClient(interceptors...))
+}
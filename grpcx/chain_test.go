@@ -3,11 +3,19 @@ package grpcx
 import (
 	"context"
 	"errors"
+	"net"
+	"net/http"
 	"reflect"
 	"testing"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/arcplus/go-lib/errs"
 )
 
 // test case from: https://github.com/grpc-ecosystem/go-grpc-middleware/blob/master/chain_test.go
@@ -232,3 +240,72 @@ func TestChainStreamServer(t *testing.T) {
 		t.Fatal("handler's sent message must propagate to stream")
 	}
 }
+
+func TestErrorRoundTrip(t *testing.T) {
+	var codeQuota uint32 = 20001
+	errs.RegisterCode(codeQuota, codes.ResourceExhausted, http.StatusTooManyRequests)
+
+	tests := []struct {
+		err  error
+		code uint32
+		grpc codes.Code
+	}{
+		{errs.NewWithAlert(errs.CodeNotFound, "user not found", "user %d not found", 1), errs.CodeNotFound, codes.NotFound},
+		{errs.New(codeQuota, "quota exceeded"), codeQuota, codes.ResourceExhausted},
+		{errors.New("db down"), errs.CodeInternal, codes.Internal},
+		{status.Error(codes.Unauthenticated, "no token"), errs.CodeUnAuth, codes.Unauthenticated},
+		{status.Error(codes.Unavailable, "db unavailable"), errs.CodeInternal, codes.Unavailable},
+	}
+
+	var handlerErr error
+	failing := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return nil, handlerErr
+	}
+
+	ln := bufconn.Listen(1 << 20)
+	s := NewServer(grpc.ChainUnaryInterceptor(failing))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(ln)
+	defer s.Stop()
+
+	cc, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return ln.Dial()
+		}),
+		WithUnaryClientChain(ClientLocale, ClientErrorConvertor),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	client := healthpb.NewHealthClient(cc)
+	for _, tt := range tests {
+		handlerErr = tt.err
+
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if !errs.IsCode(err, tt.code) {
+			t.Fatalf("%v: want code %d, got %v", tt.err, tt.code, err)
+		}
+		if s, _ := status.FromError(err); s.Code() != tt.grpc {
+			t.Fatalf("%v: want gRPC code %s, got %s", tt.err, tt.grpc, s.Code())
+		}
+
+		e, ok := err.(*errs.Error)
+		if !ok {
+			t.Fatalf("want *errs.Error, got %T", err)
+		}
+		if msg, alert := status.Convert(tt.err).Message(), errs.ToError(tt.err).Alert(); e.Message() != msg || e.Alert() != alert {
+			t.Fatalf("want %q with alert %q, got %q with alert %q", msg, alert, e.Message(), e.Alert())
+		}
+	}
+
+	// alert is localized by server
+	errs.AddCatalog("zh-CN", map[string]string{"user_not_found": "用户 {{.id}} 不存在"})
+	handlerErr = errs.WithAlertArgs(errs.NewWithAlert(errs.CodeNotFound, "user_not_found", "user 1 not found"), "id", 1)
+
+	_, err = client.Check(errs.WithLocale(context.Background(), "zh-CN"), &healthpb.HealthCheckRequest{})
+	if e, ok := err.(*errs.Error); !ok || e.Alert() != "用户 1 不存在" || e.Locale() != "zh-CN" {
+		t.Fatalf("want localized alert, got %v", err)
+	}
+}
@@ -4,29 +4,45 @@ import (
 	"context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"github.com/arcplus/go-lib/errs"
+	"github.com/arcplus/go-lib/log"
 )
 
-type grpcErrorWrapper struct {
-	s *status.Status
-}
+// ClientTrace propagates trace id and traceparent of logger in ctx to outgoing metadata,
+// traceparent of a new child span is sent, it's generated if not found in ctx.
+func ClientTrace(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	l := log.Ctx(ctx)
 
-func (e *grpcErrorWrapper) Code() uint32 {
-	return uint32(e.s.Code())
-}
+	tp := l.TraceParent()
+	if tp.IsValid() {
+		tp = tp.Child()
+	} else {
+		tp = log.NewTraceParent()
+	}
 
-func (e *grpcErrorWrapper) Message() string {
-	return e.s.Message()
-}
+	kv := []string{log.TraceParentKey, tp.String()}
+	if tid := l.TraceID(); tid != "" {
+		kv = append(kv, RequestIDKey, tid)
+	}
 
-func (e *grpcErrorWrapper) Error() string {
-	return e.s.Err().Error()
+	return invoker(metadata.AppendToOutgoingContext(ctx, kv...), method, req, reply, cc, opts...)
 }
 
-func (e *grpcErrorWrapper) GRPCStatus() *status.Status {
-	return e.s
+// ClientLocale propagates locale of ctx set by errs.WithLocale to outgoing metadata
+// AcceptLanguageKey, so alert of errors is localized by server.
+func ClientLocale(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if locale := errs.LocaleFromContext(ctx); locale != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, AcceptLanguageKey, locale)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
 }
 
+// ClientErrorConvertor convert gRPC error to *errs.Error, code, alert and other details
+// are restored from status details.
 func ClientErrorConvertor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	err := invoker(ctx, method, req, reply, cc, opts...)
 	if err != nil {
@@ -36,8 +52,8 @@ func ClientErrorConvertor(ctx context.Context, method string, req, reply interfa
 			return err
 		}
 
-		return &grpcErrorWrapper{
-			s: s,
+		if e := errs.FromGRPCStatus(s); e != nil {
+			return e
 		}
 	}
 
@@ -0,0 +1,31 @@
+package grpcx
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/arcplus/go-lib/metrics"
+)
+
+var (
+	serverHandled = metrics.NewCounterVec("grpc_server_handled_total",
+		"Total number of RPCs completed on the server.", "method", "code")
+
+	serverHandling = metrics.NewHistogramVec("grpc_server_handling_seconds",
+		"Histogram of response latency (seconds) of gRPC that had been handled by the server.", nil, "method")
+)
+
+// ServerMetrics records handled count and latency of unary RPCs.
+func ServerMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	serverHandled.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+	serverHandling.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
@@ -19,62 +19,92 @@ var (
 	MaxSendMsgSize = grpc.MaxSendMsgSize
 )
 
+// RequestIDKey is metadata key of trace id.
+const RequestIDKey = "x-request-id"
+
+// AcceptLanguageKey is metadata key of locales accepted by client, same as HTTP Accept-Language.
+const AcceptLanguageKey = "accept-language"
+
 // NewServer is helper func to create *grpc.Server
 func NewServer(opts ...grpc.ServerOption) *grpc.Server {
-	opts = append([]grpc.ServerOption{WithUnaryServerChain(ServerErrorConvertor)}, opts...)
+	opts = append([]grpc.ServerOption{WithUnaryServerChain(ServerMetrics, ServerErrorConvertor)}, opts...)
 	return grpc.NewServer(opts...)
 }
 
-// ServerErrorConvertor convert *Error to gRPC error
+// ServerErrorConvertor convert *Error to gRPC error, alert is localized by AcceptLanguageKey
+// metadata, the locale can be got by errs.LocaleFromContext(ctx) in handler.
 func ServerErrorConvertor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	tid := "x-request-id"
+	var tid, traceparent string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if t := md.Get("x-request-id"); len(t) != 0 {
+		if t := md.Get(RequestIDKey); len(t) != 0 {
 			tid = t[0]
 		}
+		if t := md.Get(log.TraceParentKey); len(t) != 0 {
+			traceparent = t[0]
+		}
+		if t := md.Get(AcceptLanguageKey); len(t) != 0 {
+			if locale := errs.MatchLocale(t[0]); locale != "" {
+				ctx = errs.WithLocale(ctx, locale)
+			}
+		}
 	}
 
-	logger := log.Trace(tid)
+	ctxLogger := log.FromRequest(tid, traceparent)
+	logger := ctxLogger.Named("grpcx")
 
-	// TODO using pool
-	buf := &bytes.Buffer{}
-	buf.WriteString("method: ")
-	buf.WriteString(info.FullMethod)
+	// dump formats req and resp (or err) of the event, it's called only if the event is
+	// written, as marshaling and redaction of payloads are costly.
+	dump := func(resp interface{}, err error) string {
+		// TODO using pool
+		buf := &bytes.Buffer{}
+		buf.WriteString("method: ")
+		buf.WriteString(info.FullMethod)
 
-	buf.WriteString("\nreq: ")
-	buf.Write(pb.MustMarshal(req.(pb.Message)))
+		buf.WriteString("\nreq: ")
+		buf.Write(log.RedactJSON(pb.MustMarshal(req.(pb.Message))))
+
+		switch {
+		case err != nil:
+			buf.WriteString("\nerr: ")
+			buf.WriteString(errs.StackTrace(err))
+		case resp != nil:
+			buf.WriteString("\nresp: ")
+			buf.Write(log.RedactJSON(pb.MustMarshal(resp.(pb.Message))))
+		}
+		return buf.String()
+	}
 
 	// recover
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Skip(1).Errorf("grpc panic recover: %s\nerr: %v\nstack:\n%s", buf.String(), r, log.TakeStacktrace())
+			logger.Skip(1).Errorf("grpc panic recover: %s\nerr: %v\nstack:\n%s", dump(nil, nil), r, log.TakeStacktrace())
 			// if panic, set custom error to 'err', in order that client and sense it.
 			err = status.Errorf(codes.Internal, "panic: %v", r)
 		}
 	}()
 
-	resp, err = handler(context.WithValue(ctx, "x-request-id", tid), req)
+	// logger can be got by log.Ctx(ctx) in handler
+	resp, err = handler(log.WithContext(ctx, ctxLogger), req)
 
 	var code uint32
+	handlerErr := err
 	if err != nil {
-		buf.WriteString("\nerr: ")
-		buf.WriteString(errs.StackTrace(err))
-
-		// convert normal error to gRPC error
-		if _, ok := status.FromError(err); !ok {
-			e := errs.ToError(err)
+		// convert normal error to gRPC error, code, localized alert and other details
+		// of *errs.Error are encoded as status details
+		_, isErr := err.(*errs.Error)
+		if s, ok := status.FromError(err); ok && !isErr {
+			code = errs.FromGRPCCode(s.Code())
+		} else {
+			e := errs.Localize(ctx, err).(*errs.Error)
 			code = e.Code()
-			err = status.Error(codes.Code(e.Code()), e.Message())
+			err = e.GRPCStatus().Err()
 		}
-	} else {
-		buf.WriteString("\nresp: ")
-		buf.Write(pb.MustMarshal(resp.(pb.Message)))
 	}
 
 	if logger.DebugEnabled() {
-		logger.Debug(buf.String())
-	} else if err != nil && code < errs.CodeBadRequest {
-		logger.Error(buf.String())
+		logger.Debug(dump(resp, handlerErr))
+	} else if err != nil && errs.HTTPStatus(code) >= 500 {
+		logger.Error(dump(resp, handlerErr))
 	}
 
 	return resp, err
@@ -9,6 +9,9 @@ import (
 	"github.com/arcplus/go-lib/now"
 )
 
+// errSampler collapses identical errors, e.g. when db is down.
+var errSampler = &log.DedupSampler{Window: time.Second}
+
 // Hooks satisfies the sqlhook.Hooks interface
 type Hook struct{}
 
@@ -21,15 +24,11 @@ func (h *Hook) Before(ctx context.Context, query string, args ...interface{}) (c
 func (h *Hook) After(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
 	td := time.Since(ctx.Value("x-sql-begin").(time.Time))
 
-	logger := log.KVPair(map[string]interface{}{
+	logger := log.Ctx(ctx).Named("sql").KVPair(map[string]interface{}{
 		"span": "sql",
 		"took": now.NanoToMs(td.Nanoseconds()),
 	})
 
-	if tid, ok := ctx.Value("x-request-id").(string); ok {
-		logger = logger.Trace(tid)
-	}
-
 	logger.Debugf("> %s. %q", query, args)
 	return ctx, nil
 }
@@ -42,15 +41,11 @@ func (h *Hook) OnError(ctx context.Context, err error, query string, args ...int
 
 	td := time.Since(ctx.Value("x-sql-begin").(time.Time))
 
-	logger := log.KVPair(map[string]interface{}{
+	logger := log.Ctx(ctx).Named("sql").Sample(errSampler).KVPair(map[string]interface{}{
 		"span": "sql",
 		"took": now.NanoToMs(td.Nanoseconds()),
 	})
 
-	if tid, ok := ctx.Value("x-request-id").(string); ok {
-		logger = logger.Trace(tid)
-	}
-
 	logger.Errorf("> %s. %q, err: %s", query, args, err)
 	return err
 }
@@ -0,0 +1,54 @@
+package sqli
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	"github.com/arcplus/go-lib/metrics"
+)
+
+// StatsCollector collects sql.DBStats of registered dbs.
+type StatsCollector struct {
+	dbs func() map[string]*sqlx.DB
+
+	open     *metrics.Desc
+	inUse    *metrics.Desc
+	idle     *metrics.Desc
+	waitCnt  *metrics.Desc
+	waitTime *metrics.Desc
+}
+
+// NewStatsCollector create collector, dbs returns registered dbs keyed by name.
+func NewStatsCollector(driverName string, dbs func() map[string]*sqlx.DB) *StatsCollector {
+	labels := []string{"db"}
+	constLabels := metrics.Labels{"driver": driverName}
+
+	return &StatsCollector{
+		dbs:      dbs,
+		open:     metrics.NewDesc("sql_open_connections", "The number of established connections both in use and idle.", labels, constLabels),
+		inUse:    metrics.NewDesc("sql_in_use_connections", "The number of connections currently in use.", labels, constLabels),
+		idle:     metrics.NewDesc("sql_idle_connections", "The number of idle connections.", labels, constLabels),
+		waitCnt:  metrics.NewDesc("sql_wait_count_total", "The total number of connections waited for.", labels, constLabels),
+		waitTime: metrics.NewDesc("sql_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", labels, constLabels),
+	}
+}
+
+// Describe implements metrics.Collector
+func (c *StatsCollector) Describe(ch chan<- *metrics.Desc) {
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCnt
+	ch <- c.waitTime
+}
+
+// Collect implements metrics.Collector
+func (c *StatsCollector) Collect(ch chan<- metrics.Metric) {
+	for name, db := range c.dbs() {
+		s := db.Stats()
+		ch <- metrics.MustNewGauge(c.open, float64(s.OpenConnections), name)
+		ch <- metrics.MustNewGauge(c.inUse, float64(s.InUse), name)
+		ch <- metrics.MustNewGauge(c.idle, float64(s.Idle), name)
+		ch <- metrics.MustNewCounter(c.waitCnt, float64(s.WaitCount), name)
+		ch <- metrics.MustNewCounter(c.waitTime, s.WaitDuration.Seconds(), name)
+	}
+}
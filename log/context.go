@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+)
+
+// ctxKey is context key of Log.
+type ctxKey struct{}
+
+// LegacyTraceKey is string ctx key of trace id used before Log was attached to ctx,
+// WithContext still sets it and Ctx falls back to it.
+//
+// Deprecated: use Ctx and TraceID, it will be removed in next release.
+const LegacyTraceKey = "x-request-id"
+
+// WithContext returns a copy of ctx with l attached, trace id of l is also set by
+// LegacyTraceKey.
+func WithContext(ctx context.Context, l Log) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if l.tid != "" {
+		ctx = context.WithValue(ctx, LegacyTraceKey, l.tid)
+	}
+	// limit cap, so kv appended later won't share backing array
+	l.kv = l.kv[:len(l.kv):len(l.kv)]
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// Ctx returns Log attached to ctx, default logger with trace id of LegacyTraceKey is
+// returned if not found.
+func Ctx(ctx context.Context) Log {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(Log); ok {
+			return l
+		}
+		if tid, ok := ctx.Value(LegacyTraceKey).(string); ok && tid != "" {
+			return logger.Trace(tid)
+		}
+	}
+	return logger
+}
+
+// TraceID returns trace id of Log attached to ctx.
+func TraceID(ctx context.Context) string {
+	return Ctx(ctx).tid
+}
+
+// TraceID returns trace id of l.
+func (l Log) TraceID() string {
+	return l.tid
+}
+
+// NewTraceID returns random trace id in hex.
+func NewTraceID() string {
+	return randHex(16)
+}
+
+// FromRequest returns logger of incoming request, trace id is requestID or trace id of
+// traceparent, span id is a new child span of traceparent, missing ones are generated.
+func FromRequest(requestID, traceparent string) Log {
+	tp, ok := ParseTraceParent(traceparent)
+	if ok {
+		tp = tp.Child()
+	} else {
+		tp = NewTraceParent()
+	}
+
+	if requestID == "" {
+		requestID = tp.TraceID
+	}
+
+	return Trace(requestID).WithTraceParent(tp)
+}
@@ -6,8 +6,8 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	diodes "code.cloudfoundry.org/go-diodes"
@@ -22,7 +22,36 @@ var bufPool = &sync.Pool{
 
 type Alerter func(missed int)
 
-// Writer is a io.Writer wrapper that uses a diode to make Write lock-free,
+// Policy is backpressure policy of async writer when its queue is full.
+type Policy int
+
+// Policy
+const (
+	DropOldest      Policy = iota // overwrite oldest events, default
+	DropNewest                    // drop new events
+	Block                         // block producers until queue has room or BlockTimeout
+	NeverDropErrors               // DropOldest, error and fatal events bypass queue
+)
+
+// AsyncConfig is conf for async writer.
+type AsyncConfig struct {
+	Name         string // name in AsyncWriterStats, default "async"
+	Level        Level
+	Size         int           // queue size, default 1000
+	PollInterval time.Duration // default 10ms, used by DropOldest and NeverDropErrors
+	Policy       Policy
+	BlockTimeout time.Duration // max wait of Block, default 100ms
+	Alerter      Alerter       // called when events are dropped
+}
+
+// AsyncStats is stats of async writer.
+type AsyncStats struct {
+	Written uint64 // events written to wrapped writer
+	Dropped uint64 // events dropped
+	Queued  int    // events in queue
+}
+
+// Writer is a io.Writer wrapper that uses a diode or channel to make Write
 // non-blocking and thread safe.
 type Writer struct {
 	lv   Level
@@ -31,6 +60,23 @@ type Writer struct {
 	p    *diodes.Poller
 	c    context.CancelFunc
 	done chan struct{}
+	s    *asyncState
+}
+
+type asyncState struct {
+	name    string
+	policy  Policy
+	timeout time.Duration
+	alert   Alerter
+	ch      chan asyncEntry // DropNewest, Block
+	closed  <-chan struct{} // closed on Close
+
+	mu sync.Mutex // guards wrapped writer
+
+	enqueued uint64
+	bypassed uint64
+	written  uint64
+	dropped  uint64
 }
 
 // NewAsyncWriter creates a writer wrapping w with a many-to-one diode in order to
@@ -47,28 +93,72 @@ type Writer struct {
 //
 // See code.cloudfoundry.org/go-diodes for more info on diode.
 func NewAsyncWriter(lv Level, w io.Writer, size int, poolInterval time.Duration, f Alerter) Writer {
+	return NewAsyncWriterWithConfig(w, AsyncConfig{
+		Level:        lv,
+		Size:         size,
+		PollInterval: poolInterval,
+		Alerter:      f,
+	})
+}
+
+// NewAsyncWriterWithConfig creates a async writer wrapping w with backpressure policy
+// of conf.
+func NewAsyncWriterWithConfig(w io.Writer, conf AsyncConfig) Writer {
+	if conf.Size <= 0 {
+		conf.Size = 1000
+	}
+
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = 10 * time.Millisecond
+	}
+
+	if conf.BlockTimeout <= 0 {
+		conf.BlockTimeout = 100 * time.Millisecond
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	d := diodes.NewManyToOne(size, diodes.AlertFunc(f))
+
+	s := &asyncState{
+		policy:  conf.Policy,
+		timeout: conf.BlockTimeout,
+		alert:   conf.Alerter,
+		closed:  ctx.Done(),
+	}
+
 	dw := Writer{
-		lv: lv,
-		w:  w,
-		d:  d,
-		p: diodes.NewPoller(d,
-			diodes.WithPollingInterval(poolInterval),
-			diodes.WithPollingContext(ctx)),
+		lv:   conf.Level,
+		w:    w,
 		c:    cancel,
 		done: make(chan struct{}),
+		s:    s,
 	}
+
+	switch conf.Policy {
+	case DropNewest, Block:
+		s.ch = make(chan asyncEntry, conf.Size)
+	default:
+		dw.d = diodes.NewManyToOne(conf.Size, diodes.AlertFunc(s.drop))
+		dw.p = diodes.NewPoller(dw.d,
+			diodes.WithPollingInterval(conf.PollInterval),
+			diodes.WithPollingContext(ctx))
+	}
+
+	registerAsync(conf.Name, s, dw.Stats)
+
 	go dw.poll()
 	return dw
 }
 
+// asyncEntry is a queued event, level is kept so WriteLevel of wrapped writer
+// gets it, e.g. syslog severity.
+type asyncEntry struct {
+	p       []byte
+	level   Level
+	leveled bool
+}
+
 func (dw Writer) Write(p []byte) (n int, err error) {
-	// p is pooled in zerolog so we can't hold it passed this call, hence the
-	// copy.
-	p = append(bufPool.Get().([]byte), p...)
-	dw.d.Set(diodes.GenericDataType(&p))
-	return len(p), nil
+	return dw.enqueue(asyncEntry{p: p})
 }
 
 func (dw Writer) WriteLevel(level Level, p []byte) (n int, err error) {
@@ -76,7 +166,74 @@ func (dw Writer) WriteLevel(level Level, p []byte) (n int, err error) {
 		return len(p), nil
 	}
 
-	return dw.Write(p)
+	e := asyncEntry{p: p, level: level, leveled: true}
+
+	if dw.s.policy == NeverDropErrors && (level == ErrorLevel || level == FatalLevel) {
+		atomic.AddUint64(&dw.s.bypassed, 1)
+		dw.write(e)
+		return len(p), nil
+	}
+
+	return dw.enqueue(e)
+}
+
+func (dw Writer) enqueue(e asyncEntry) (n int, err error) {
+	n = len(e.p)
+
+	// p is pooled in zerolog so we can't hold it passed this call, hence the
+	// copy.
+	e.p = append(bufPool.Get().([]byte), e.p...)
+
+	if dw.s.ch == nil {
+		atomic.AddUint64(&dw.s.enqueued, 1)
+		dw.d.Set(diodes.GenericDataType(&e))
+		return n, nil
+	}
+
+	select {
+	case dw.s.ch <- e:
+		return n, nil
+	default:
+	}
+
+	if dw.s.policy == Block {
+		t := time.NewTimer(dw.s.timeout)
+		defer t.Stop()
+
+		select {
+		case dw.s.ch <- e:
+			return n, nil
+		case <-t.C:
+		case <-dw.s.closed:
+		}
+	}
+
+	bufPool.Put(e.p[:0])
+	dw.s.drop(1)
+	return n, nil
+}
+
+// Stats returns stats of dw.
+func (dw Writer) Stats() AsyncStats {
+	st := AsyncStats{
+		Written: atomic.LoadUint64(&dw.s.written),
+		Dropped: atomic.LoadUint64(&dw.s.dropped),
+	}
+
+	if dw.s.ch != nil {
+		st.Queued = len(dw.s.ch)
+		return st
+	}
+
+	// bypassed events are not enqueued
+	queued := int64(atomic.LoadUint64(&dw.s.enqueued)) - int64(atomic.LoadUint64(&dw.s.written)) - int64(st.Dropped)
+	if dw.s.policy == NeverDropErrors {
+		queued += int64(atomic.LoadUint64(&dw.s.bypassed))
+	}
+	if queued > 0 {
+		st.Queued = int(queued)
+	}
+	return st
 }
 
 // Close releases the diode poller and call Close on the wrapped writer if
@@ -84,52 +241,104 @@ func (dw Writer) WriteLevel(level Level, p []byte) (n int, err error) {
 func (dw Writer) Close() error {
 	dw.c()
 	<-dw.done
+	unregisterAsync(dw.s)
 	if w, ok := dw.w.(io.Closer); ok {
 		return w.Close()
 	}
 	return nil
 }
 
+// write writes e to wrapped writer, by WriteLevel if it's leveled.
+func (dw Writer) write(e asyncEntry) {
+	dw.s.mu.Lock()
+	if lw, ok := dw.w.(zerolog.LevelWriter); ok && e.leveled {
+		lw.WriteLevel(e.level, e.p)
+	} else {
+		dw.w.Write(e.p)
+	}
+	dw.s.mu.Unlock()
+	atomic.AddUint64(&dw.s.written, 1)
+}
+
 func (dw Writer) poll() {
 	defer close(dw.done)
+
+	if dw.s.ch != nil {
+		for {
+			select {
+			case e := <-dw.s.ch:
+				dw.write(e)
+				bufPool.Put(e.p[:0])
+			case <-dw.s.closed:
+				// flush queued
+				for {
+					select {
+					case e := <-dw.s.ch:
+						dw.write(e)
+						bufPool.Put(e.p[:0])
+					default:
+						return
+					}
+				}
+			}
+		}
+	}
+
 	for {
 		d := dw.p.Next()
 		if d == nil {
 			return
 		}
-		p := *(*[]byte)(d)
-		dw.w.Write(p)
-		bufPool.Put(p[:0])
+		e := *(*asyncEntry)(d)
+		dw.write(e)
+		bufPool.Put(e.p[:0])
+	}
+}
+
+func (s *asyncState) drop(missed int) {
+	atomic.AddUint64(&s.dropped, uint64(missed))
+	if s.alert != nil {
+		s.alert(missed)
 	}
 }
 
-// ConsoleConfig is conf for redis writer.
+// ConsoleConfig is conf for console writer.
 type ConsoleConfig struct {
-	Async bool
+	Async  bool
+	Policy Policy // backpressure policy if Async
+	Format Format // default is format set by SetFormat
 }
 
+// ConsoleWriter writes to stdout in conf.Format.
 func ConsoleWriter(conf ConsoleConfig) io.Writer {
+	if conf.Format == "" {
+		conf.Format = GetFormat()
+	}
+
 	if conf.Async {
-		wr := NewAsyncWriter(0, zerolog.ConsoleWriter{
-			Out:             os.Stdout,
-			FormatTimestamp: consoleTimeFormatter,
-		}, 1000, 10*time.Millisecond, func(missed int) {
-			log.Printf("Console Writer dropped %d messages", missed)
+		wr := NewAsyncWriterWithConfig(stdWriter(conf.Format), AsyncConfig{
+			Name:   "console",
+			Policy: conf.Policy,
+			Alerter: func(missed int) {
+				log.Printf("Console Writer dropped %d messages", missed)
+			},
 		})
 
-		asyncWaitList = append(asyncWaitList, func() error {
-			return wr.Close()
-		})
+		addCloser(wr.Close)
 
 		return wr
 	}
-	return zerolog.ConsoleWriter{Out: os.Stdout}
+	return stdWriter(conf.Format)
 }
 
 func consoleTimeFormatter(i interface{}) string {
-	if i, ok := i.(json.Number); ok {
-		s, err := i.Int64()
-		if err == nil {
+	switch i := i.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, i); err == nil {
+			return t.Local().Format(time.RFC3339)
+		}
+	case json.Number:
+		if s, err := i.Int64(); err == nil {
 			return time.Unix(s, 0).Format(time.RFC3339)
 		}
 	}
@@ -0,0 +1,342 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileConfig is conf for file writer.
+type FileConfig struct {
+	Level      Level
+	Filename   string
+	MaxSize    int           // megabytes, rotate when file exceeds it, 0 means no limit
+	Interval   time.Duration // rotate every interval aligned to UTC, e.g. 24h, 0 means no limit
+	MaxBackups int           // max rotated files to keep, 0 means keep all
+	Compress   bool          // gzip rotated files
+	Async      bool
+	Policy     Policy // backpressure policy if Async
+}
+
+// ReopenSignal notify file writers to reopen file, used by logrotate.
+var ReopenSignal = []os.Signal{syscall.SIGHUP}
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotateRetryInterval is the time to wait before retrying failed rotation.
+const rotateRetryInterval = time.Minute
+
+var errFileClosed = errors.New("log file closed")
+
+// fileWriter writes to file and rotates it by size and time.
+type fileWriter struct {
+	mu   sync.Mutex
+	conf FileConfig
+	file *os.File
+	size int64
+	next time.Time // next time to rotate
+
+	retryAt time.Time // rotation failed, retry after it
+
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+
+	closed bool
+	stop   chan struct{}
+}
+
+// FileWriter file writer, it panics if file can't be opened.
+func FileWriter(conf FileConfig) io.Writer {
+	if conf.Filename == "" {
+		conf.Filename = filepath.Base(os.Args[0]) + ".log"
+	}
+
+	fw := &fileWriter{
+		conf: conf,
+		stop: make(chan struct{}),
+	}
+
+	if err := fw.open(); err != nil {
+		panic(err)
+	}
+
+	go fw.watchSignal()
+
+	return asyncOrSync("file", conf.Async, conf.Level, conf.Policy, fw)
+}
+
+// Write write data to writer
+func (fw *fileWriter) Write(p []byte) (n int, err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.closed {
+		return 0, errFileClosed
+	}
+
+	if fw.shouldRotate(len(p)) {
+		// keep writing to current file if rotation fails
+		if err := fw.rotate(); err != nil {
+			fw.retryAt = time.Now().Add(rotateRetryInterval)
+			log.Printf("File Writer rotate err: %s, retry in %s", err, rotateRetryInterval)
+		}
+	}
+
+	n, err = fw.file.Write(p)
+	fw.size += int64(n)
+	return n, err
+}
+
+// WriteLevel write data to writer with level info provided
+func (fw *fileWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if level < fw.conf.Level {
+		return len(p), nil
+	}
+
+	return fw.Write(p)
+}
+
+// Rotate rotates file immediately.
+func (fw *fileWriter) Rotate() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.closed {
+		return errFileClosed
+	}
+
+	return fw.rotate()
+}
+
+// Reopen reopens file, file may be moved by logrotate. The old file is kept if it fails.
+func (fw *fileWriter) Reopen() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.closed {
+		return errFileClosed
+	}
+
+	return fw.open()
+}
+
+// Close closes file and waits for compressing.
+func (fw *fileWriter) Close() error {
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return nil
+	}
+	fw.closed = true
+	close(fw.stop)
+	err := fw.file.Close()
+	fw.mu.Unlock()
+
+	fw.millWg.Wait()
+	return err
+}
+
+func (fw *fileWriter) watchSignal() {
+	if len(ReopenSignal) == 0 {
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, ReopenSignal...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ch:
+			if err := fw.Reopen(); err != nil && err != errFileClosed {
+				log.Printf("File Writer reopen err: %s", err)
+			}
+		case <-fw.stop:
+			return
+		}
+	}
+}
+
+func (fw *fileWriter) shouldRotate(n int) bool {
+	if time.Now().Before(fw.retryAt) {
+		return false
+	}
+
+	if fw.conf.MaxSize > 0 && fw.size > 0 && fw.size+int64(n) > int64(fw.conf.MaxSize)*1024*1024 {
+		return true
+	}
+
+	return fw.conf.Interval > 0 && !time.Now().Before(fw.next)
+}
+
+// open opens or creates file, the old one is closed only if it succeeds, fw.mu must be held.
+func (fw *fileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(fw.conf.Filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(fw.conf.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if fw.file != nil {
+		fw.file.Close()
+	}
+
+	fw.file = f
+	fw.size = info.Size()
+
+	if fw.conf.Interval > 0 {
+		fw.next = time.Now().Truncate(fw.conf.Interval).Add(fw.conf.Interval)
+	}
+
+	return nil
+}
+
+// rotate renames current file to backup and opens a new one, the backup is renamed back
+// if it fails, fw.mu must be held.
+func (fw *fileWriter) rotate() error {
+	ext := filepath.Ext(fw.conf.Filename)
+	base := strings.TrimSuffix(fw.conf.Filename, ext) + "-" + time.Now().Format(backupTimeFormat)
+
+	// sequence is added if rotated within a millisecond
+	backup := base + ext
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = base + "_" + strconv.Itoa(i) + ext
+	}
+
+	if err := os.Rename(fw.conf.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := fw.open(); err != nil {
+		os.Rename(backup, fw.conf.Filename)
+		return err
+	}
+
+	fw.millWg.Add(1)
+	go fw.mill(backup)
+
+	return nil
+}
+
+// mill compresses backup and removes old backups.
+func (fw *fileWriter) mill(backup string) {
+	defer fw.millWg.Done()
+
+	fw.millMu.Lock()
+	defer fw.millMu.Unlock()
+
+	if fw.conf.Compress {
+		if err := compressFile(backup); err != nil {
+			log.Printf("File Writer compress %s err: %s", backup, err)
+		}
+	}
+
+	if fw.conf.MaxBackups <= 0 {
+		return
+	}
+
+	backups, err := fw.backups()
+	if err != nil {
+		log.Printf("File Writer list backups err: %s", err)
+		return
+	}
+
+	for i := 0; i < len(backups)-fw.conf.MaxBackups; i++ {
+		os.Remove(backups[i])
+	}
+}
+
+// backups returns rotated files, oldest first.
+func (fw *fileWriter) backups() ([]string, error) {
+	ext := filepath.Ext(fw.conf.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(fw.conf.Filename), ext) + "-"
+	dir := filepath.Dir(fw.conf.Filename)
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ts := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		if i := strings.LastIndexByte(ts, '_'); i != -1 {
+			ts = ts[:i] // sequence
+		}
+		if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+			continue
+		}
+
+		backups = append(backups, filepath.Join(dir, name))
+	}
+
+	// time format sorts by name
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+
+	return backups, nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
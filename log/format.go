@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/rs/zerolog"
+
+	"github.com/arcplus/go-lib/pool"
+)
+
+// Format is output format of log.
+//
+// Fields of json and logfmt format are stable:
+//
+//	ts       time in RFC3339Nano
+//	level    debug, info, warn, error, fatal
+//	msg      message
+//	tid      trace id, if any
+//	caller   file:line, if caller is enabled
+//	logger   name of named logger, if any
+//	service  service name, set by SetAttachment
+//	mode     run mode, set by SetAttachment
+//	version  version, set by SetAttachment
+//	stack    stack trace, if WithStack is used
+type Format string
+
+// Format
+const (
+	FormatConsole Format = "console" // human readable, default
+	FormatJSON    Format = "json"
+	FormatLogfmt  Format = "logfmt"
+)
+
+var format = FormatConsole
+
+// SetFormat set output format, it also resets output to stdout, so it should be called
+// before SetOutput. Unknown format is treated as FormatConsole.
+func SetFormat(f Format) {
+	if f != FormatJSON && f != FormatLogfmt {
+		f = FormatConsole
+	}
+
+	cfgMu.Lock()
+	format = f
+	cfgMu.Unlock()
+
+	SetOutput(stdWriter(f))
+}
+
+// GetFormat returns output format.
+func GetFormat() Format {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	return format
+}
+
+// stdWriter returns stdout writer in format f.
+func stdWriter(f Format) io.Writer {
+	switch f {
+	case FormatJSON:
+		return os.Stdout
+	case FormatLogfmt:
+		return LogfmtWriter(os.Stdout)
+	default:
+		return zerolog.ConsoleWriter{
+			Out:             os.Stdout,
+			FormatTimestamp: consoleTimeFormatter,
+		}
+	}
+}
+
+type logfmtWriter struct {
+	w io.Writer
+}
+
+// LogfmtWriter converts json log to logfmt and writes to w.
+func LogfmtWriter(w io.Writer) io.Writer {
+	return logfmtWriter{w: w}
+}
+
+// Write write data to writer
+func (lw logfmtWriter) Write(p []byte) (n int, err error) {
+	m := map[string]interface{}{}
+
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return 0, err
+	}
+
+	buf := bufferPool.Get()
+	defer buf.Free()
+
+	// ts, level, msg go first, others are sorted by key
+	for _, k := range [...]string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName} {
+		if v, ok := m[k]; ok {
+			appendLogfmt(buf, k, v)
+			delete(m, k)
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		appendLogfmt(buf, k, m[k])
+	}
+
+	buf.AppendByte('\n')
+
+	if _, err := lw.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func appendLogfmt(buf *pool.Buffer, k string, v interface{}) {
+	if buf.Len() != 0 {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(k)
+	buf.AppendByte('=')
+
+	var s string
+	switch vv := v.(type) {
+	case string:
+		s = vv
+	case json.Number:
+		buf.AppendString(vv.String())
+		return
+	case bool:
+		buf.AppendBool(vv)
+		return
+	case nil:
+		return
+	default:
+		b, err := json.Marshal(vv)
+		if err != nil {
+			s = fmt.Sprint(vv)
+		} else {
+			s = string(b)
+		}
+	}
+
+	if needQuote(s) {
+		buf.AppendString(strconv.Quote(s))
+		return
+	}
+	buf.AppendString(s)
+}
+
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == '=' || c == '"' || c >= 0x7f {
+			return true
+		}
+	}
+	return false
+}
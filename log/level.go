@@ -0,0 +1,179 @@
+package log
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// levelUnset means named logger follows global level.
+const levelUnset = int32(-128)
+
+var globalLevel = int32(DebugLevel)
+
+// namedLevel is effective level of named logger, changed by SetLevel.
+type namedLevel struct {
+	lv int32
+}
+
+var levels = struct {
+	sync.RWMutex
+	rules map[string]Level // pattern -> level
+	named map[string]*namedLevel
+}{
+	rules: map[string]Level{},
+	named: map[string]*namedLevel{},
+}
+
+// Named returns logger named name, its level can be set by SetLevel, global level is
+// used if not set.
+func Named(name string) Log {
+	l := logger
+	l.name = name
+	l.level = getNamedLevel(name)
+	return l
+}
+
+// Named returns copy of l named name.
+func (l Log) Named(name string) Log {
+	l.name = name
+	l.level = getNamedLevel(name)
+	return l
+}
+
+// SetLevel set level of named loggers matching pattern, pattern is name or glob
+// (see path.Match), e.g. "mysql", "grpc*". Exact name takes precedence over glob,
+// longer glob takes precedence over shorter one.
+func SetLevel(pattern string, lv Level) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	levels.Lock()
+	levels.rules[pattern] = lv
+	updateLevels()
+	levels.Unlock()
+	return nil
+}
+
+// UnsetLevel removes level set by SetLevel, loggers matching pattern follow global level again.
+func UnsetLevel(pattern string) {
+	levels.Lock()
+	delete(levels.rules, pattern)
+	updateLevels()
+	levels.Unlock()
+}
+
+// Levels returns levels set by SetLevel.
+func Levels() map[string]Level {
+	levels.RLock()
+	defer levels.RUnlock()
+
+	m := make(map[string]Level, len(levels.rules))
+	for k, v := range levels.rules {
+		m[k] = v
+	}
+	return m
+}
+
+// NamedLevel returns effective level of named logger, name is not recorded, so it's safe
+// with names from requests.
+func NamedLevel(name string) Level {
+	levels.RLock()
+	lv := matchLevel(name)
+	levels.RUnlock()
+
+	if lv != levelUnset {
+		return Level(lv)
+	}
+	return Level(atomic.LoadInt32(&globalLevel))
+}
+
+// GetLevel returns effective level of l.
+func (l Log) GetLevel() Level {
+	if l.level != nil {
+		if lv := atomic.LoadInt32(&l.level.lv); lv != levelUnset {
+			return Level(lv)
+		}
+	}
+	return Level(atomic.LoadInt32(&globalLevel))
+}
+
+// enabled checks if lv is enabled for l.
+func (l Log) enabled(lv Level) bool {
+	return lv >= l.GetLevel() && lv != Disabled
+}
+
+func getNamedLevel(name string) *namedLevel {
+	levels.RLock()
+	nl, ok := levels.named[name]
+	levels.RUnlock()
+	if ok {
+		return nl
+	}
+
+	levels.Lock()
+	defer levels.Unlock()
+
+	if nl, ok = levels.named[name]; ok {
+		return nl
+	}
+
+	nl = &namedLevel{lv: matchLevel(name)}
+	levels.named[name] = nl
+	return nl
+}
+
+// updateLevels recomputes named loggers level, levels lock must be held.
+func updateLevels() {
+	for name, nl := range levels.named {
+		atomic.StoreInt32(&nl.lv, matchLevel(name))
+	}
+	updateZerologLevel()
+}
+
+// matchLevel returns level of best rule matching name, levels lock must be held.
+func matchLevel(name string) int32 {
+	if lv, ok := levels.rules[name]; ok {
+		return int32(lv)
+	}
+
+	patterns := make([]string, 0, len(levels.rules))
+	for p := range levels.rules {
+		if strings.ContainsAny(p, "*?[\\") {
+			patterns = append(patterns, p)
+		}
+	}
+
+	// more specific first
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return int32(levels.rules[p])
+		}
+	}
+
+	return levelUnset
+}
+
+// updateZerologLevel set zerolog global level to min level in use, so events are filtered
+// by levelLog only, levels lock must be held.
+func updateZerologLevel() {
+	min := Level(atomic.LoadInt32(&globalLevel))
+	for _, lv := range levels.rules {
+		if lv < min {
+			min = lv
+		}
+	}
+	zerolog.SetGlobalLevel(min)
+}
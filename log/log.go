@@ -1,12 +1,15 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -40,22 +43,50 @@ type BasicSampler = zerolog.BasicSampler
 // Log struct.
 type Log struct {
 	mu           *sync.RWMutex
-	zl           *zerolog.Logger
+	zl           *zerolog.Logger // nil means global zerolog logger
 	sampler      zerolog.Sampler
 	depth        int
 	callerEnable bool
 	stackEnable  bool
+	tid          string
+	tp           TraceParent
+	name         string
+	level        *namedLevel   // nil means global level
 	kv           []interface{} // len must be even
 }
 
-var zl = zerolog.New(zerolog.ConsoleWriter{
-	Out:             os.Stdout,
-	FormatTimestamp: consoleTimeFormatter,
-}).With().Timestamp().Logger()
+// global config, swapped atomically so it can be changed while logging
+var (
+	cfgMu  sync.Mutex   // serializes SetXXX
+	output atomic.Value // io.Writer wrapped by outputHolder
+	gzl    atomic.Value // *zerolog.Logger
+)
+
+type outputHolder struct {
+	w io.Writer
+}
+
+func init() {
+	w := zerolog.ConsoleWriter{
+		Out:             os.Stdout,
+		FormatTimestamp: consoleTimeFormatter,
+	}
+	zl := zerolog.New(w).With().Timestamp().Logger()
+
+	output.Store(outputHolder{w: w})
+	gzl.Store(&zl)
+}
 
 var logger = Log{
 	mu: &sync.RWMutex{},
-	zl: &zl,
+}
+
+// getZl returns zerolog logger of l.
+func (l Log) getZl() *zerolog.Logger {
+	if l.zl != nil {
+		return l.zl
+	}
+	return gzl.Load().(*zerolog.Logger)
 }
 
 // prefixSize is used internally to trim the user specific path from the
@@ -65,7 +96,8 @@ var prefixSize int
 func init() {
 	zerolog.MessageFieldName = "msg"
 	zerolog.TimestampFieldName = "ts"
-	zerolog.TimeFieldFormat = ""
+	// set once, SetFormat can't change it as it's read by logging without lock
+	zerolog.TimeFieldFormat = time.RFC3339Nano
 
 	_, file, _, ok := runtime.Caller(0)
 	if file == "?" {
@@ -85,31 +117,70 @@ func Logger() Log {
 	return l
 }
 
-// SetOutput set multi log writer, careful, all SetXXX method are non-thread safe.
+// SetOutput set multi log writer, all SetXXX method are thread safe.
 func SetOutput(w ...io.Writer) {
+	var out io.Writer
 	switch len(w) {
 	case 0:
 		return
 	case 1:
-		zl = zl.Output(w[0])
+		out = w[0]
 	default:
-		zl = zl.Output(zerolog.MultiLevelWriter(w...))
+		out = zerolog.MultiLevelWriter(w...)
 	}
+
+	cfgMu.Lock()
+	zl := gzl.Load().(*zerolog.Logger).Output(out)
+	output.Store(outputHolder{w: out})
+	gzl.Store(&zl)
+	cfgMu.Unlock()
 }
 
+// Output returns current log writer.
+func Output() io.Writer {
+	return output.Load().(outputHolder).w
+}
+
+// GlobalCallerEnable enables caller for all loggers.
+//
+// Deprecated: it's not thread safe, use SetCallerEnable instead.
 var GlobalCallerEnable = false
 
-// SetGlobalLevel set global log level.
+var callerEnable int32
+
+// SetCallerEnable enables caller for all loggers.
+func SetCallerEnable(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&callerEnable, v)
+}
+
+// SetGlobalLevel set global log level, it's used by loggers without level set by SetLevel.
 func SetGlobalLevel(l Level) {
-	zerolog.SetGlobalLevel(l)
+	atomic.StoreInt32(&globalLevel, int32(l))
+
+	levels.Lock()
+	updateZerologLevel()
+	levels.Unlock()
+}
+
+// GlobalLevel returns global log level.
+func GlobalLevel() Level {
+	return Level(atomic.LoadInt32(&globalLevel))
 }
 
 // TODO maybe we should use zerolog default depth
-var depth = 2
+var depth int32 = 2
 
 // SetCallDepth set call depth for show line number.
 func SetCallDepth(n int) {
-	depth = n
+	atomic.StoreInt32(&depth, int32(n))
+}
+
+func callDepth() int {
+	return int(atomic.LoadInt32(&depth))
 }
 
 // SetAttachment add global kv to logger
@@ -117,7 +188,10 @@ func SetAttachment(kv map[string]interface{}) {
 	if len(kv) == 0 {
 		return
 	}
-	ctx := zl.With()
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+
+	ctx := gzl.Load().(*zerolog.Logger).With()
 	for k, v := range kv {
 		switch vv := v.(type) {
 		case string:
@@ -132,7 +206,8 @@ func SetAttachment(kv map[string]interface{}) {
 			ctx = ctx.Interface(k, vv)
 		}
 	}
-	zl = ctx.Logger()
+	zl := ctx.Logger()
+	gzl.Store(&zl)
 }
 
 func Debug(v string) {
@@ -148,7 +223,7 @@ func Debugf(format string, v ...interface{}) {
 }
 
 func DebugEnabled() bool {
-	return zl.Debug().Enabled()
+	return logger.DebugEnabled()
 }
 
 func Info(v string) {
@@ -218,6 +293,14 @@ func KVPair(kv map[string]interface{}) Log {
 	return l
 }
 
+// KVPair add kv pairs to l
+func (l Log) KVPair(kv map[string]interface{}) Log {
+	for k, v := range kv {
+		l.kv = append(l.kv, k, v)
+	}
+	return l
+}
+
 // SetKV change kv slice
 func (l *Log) SetKV(k string, v interface{}) Log {
 	l.mu.Lock()
@@ -227,13 +310,27 @@ func (l *Log) SetKV(k string, v interface{}) Log {
 }
 
 func Trace(v string) Log {
-	l := logger
-	l.kv = append(l.kv, "tid", v)
-	return l
+	return logger.Trace(v)
 }
 
+// Trace returns copy of l with trace id v, previous one is replaced.
 func (l Log) Trace(v string) Log {
-	l.kv = append(l.kv, "tid", v)
+	l.tid = v
+	return l.replaceKV("tid", v)
+}
+
+// replaceKV returns copy of l with value of k replaced by v, it's appended if k not found.
+func (l Log) replaceKV(k string, v interface{}) Log {
+	for i := 0; i < len(l.kv); i += 2 {
+		if l.kv[i] == k {
+			kv := make([]interface{}, len(l.kv))
+			copy(kv, l.kv)
+			kv[i+1] = v
+			l.kv = kv
+			return l
+		}
+	}
+	l.kv = append(l.kv, k, v)
 	return l
 }
 
@@ -261,33 +358,38 @@ func (l Log) WithStack() Log {
 
 func WithHook(h Hook) Log {
 	l := logger
-	hl := l.zl.Hook(h)
+	hl := l.getZl().Hook(h)
 	l.zl = &hl
 	return l
 }
 
 func (l Log) WithHook(h Hook) Log {
-	hl := l.zl.Hook(h)
+	hl := l.getZl().Hook(h)
 	l.zl = &hl
 	return l
 }
 
 func WithHookFunc(h HookFunc) Log {
 	l := logger
-	hl := l.zl.Hook(h)
+	hl := l.getZl().Hook(h)
 	l.zl = &hl
 	return l
 }
 
 func (l Log) WithHookFunc(h HookFunc) Log {
-	hl := l.zl.Hook(h)
+	hl := l.getZl().Hook(h)
 	l.zl = &hl
 	return l
 }
 
 func Sample(sampler Sampler) Log {
-	l := logger
-	l.sampler = sampler
+	return logger.Sample(sampler)
+}
+
+// Sample returns copy of l with sampler s, it overwrites global sampler.
+func (l Log) Sample(s Sampler) Log {
+	checkSampler(s, false)
+	l.sampler = s
 	return l
 }
 
@@ -303,7 +405,7 @@ func (l Log) Skip(n int) Log {
 }
 
 func (l Log) DebugEnabled() bool {
-	return l.zl.Debug().Enabled()
+	return l.enabled(DebugLevel)
 }
 
 func (l Log) Debug(v string) {
@@ -352,14 +454,59 @@ func (l Log) Fatalf(format string, v ...interface{}) {
 }
 
 func (l Log) levelLog(lv Level, format string, v ...interface{}) {
-	evt := l.zl.WithLevel(lv)
+	if !l.enabled(lv) {
+		if lv == FatalLevel {
+			Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	sampler := l.sampler
+	if sampler == nil {
+		sampler = globalSampler()
+	}
+
+	l.depth++ // for write
 
-	if l.sampler != nil {
-		s := l.zl.Sample(l.sampler)
-		evt = s.WithLevel(lv)
+	if ds, next := dedupOf(sampler, lv); ds != nil && lv != FatalLevel {
+		var pcs [1]uintptr
+		runtime.Callers(callDepth()+l.depth, pcs[:])
+
+		msg := fmt.Sprintf(format, v...)
+		repeated, ok := ds.allow(l, lv, msg, pcs[0])
+		if !ok {
+			return
+		}
+		l.write(lv, next, repeated, 0, "%s", msg)
+		return
 	}
 
+	l.write(lv, sampler, 0, 0, format, v...)
+}
+
+// write writes event with sampler, pc is caller of the event, 0 means caller of levelLog.
+func (l Log) write(lv Level, sampler Sampler, repeated int, pc uintptr, format string, v ...interface{}) {
+	var evt *Event
+	if sampler != nil && lv != FatalLevel {
+		sl := l.getZl().Sample(sampler)
+		evt = sl.WithLevel(lv)
+	} else {
+		evt = l.getZl().WithLevel(lv)
+	}
+
+	if repeated != 0 {
+		evt.Int("repeated", repeated)
+	}
+
+	r := loadRedactor()
+
 	for i, ln := 0, len(l.kv); i < ln; i = i + 2 {
+		if r != nil {
+			r.kv(evt, l.kv[i].(string), l.kv[i+1])
+			continue
+		}
+
 		switch vv := l.kv[i+1].(type) {
 		case string:
 			evt.Str(l.kv[i].(string), vv)
@@ -374,8 +521,20 @@ func (l Log) levelLog(lv Level, format string, v ...interface{}) {
 		}
 	}
 
-	if GlobalCallerEnable || l.callerEnable {
-		_, file, line, ok := runtime.Caller(depth + l.depth)
+	if l.name != "" {
+		evt.Str("logger", l.name)
+	}
+
+	if l.callerEnable || GlobalCallerEnable || atomic.LoadInt32(&callerEnable) == 1 {
+		var file string
+		var line int
+		ok := true
+		if pc != 0 {
+			f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+			file, line, ok = f.File, f.Line, f.File != ""
+		} else {
+			_, file, line, ok = runtime.Caller(callDepth() + l.depth)
+		}
 		if ok {
 			if prefixSize != 0 && len(file) > prefixSize {
 				file = file[prefixSize:]
@@ -389,7 +548,11 @@ func (l Log) levelLog(lv Level, format string, v ...interface{}) {
 		evt.Str("stack", TakeStacktrace(l.depth))
 	}
 
-	evt.Msgf(format, v...)
+	if r != nil {
+		evt.Msg(r.str(fmt.Sprintf(format, v...)))
+	} else {
+		evt.Msgf(format, v...)
+	}
 
 	// Close then exit
 	switch lv {
@@ -399,11 +562,27 @@ func (l Log) levelLog(lv Level, format string, v ...interface{}) {
 	}
 }
 
-var asyncWaitList = []func() error{}
+// closers are closed by Close, e.g. async writers.
+var closers = struct {
+	sync.Mutex
+	list []func() error
+}{}
 
+// addCloser adds f to be called by Close.
+func addCloser(f func() error) {
+	closers.Lock()
+	closers.list = append(closers.list, f)
+	closers.Unlock()
+}
+
+// Close flushes and closes writers, it's safe to call concurrently with SetOutput.
 func Close() error {
-	for i := range asyncWaitList {
-		asyncWaitList[i]()
+	closers.Lock()
+	list := append([]func() error(nil), closers.list...)
+	closers.Unlock()
+
+	for i := range list {
+		list[i]()
 	}
 	return nil
 }
@@ -430,7 +609,7 @@ func TakeStacktrace(optionalSkip ...int) string {
 	if len(optionalSkip) != 0 {
 		skip = optionalSkip[0]
 	}
-	skip += depth + 2
+	skip += callDepth() + 2
 
 	buff := bufferPool.Get()
 	defer buff.Free()
@@ -1,11 +1,22 @@
 package log
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -111,6 +122,183 @@ func TestTrace(t *testing.T) {
 	KV("x", "y").Trace("uuid-uuid-uuid-uuid").Error("trace test3")
 }
 
+func TestContext(t *testing.T) {
+	if tid := TraceID(context.Background()); tid != "" {
+		t.Fatalf("want empty tid, got %s", tid)
+	}
+
+	ctx := WithContext(context.Background(), KV("x", "y").Trace("uuid-ctx"))
+	if tid := TraceID(ctx); tid != "uuid-ctx" {
+		t.Fatalf("want uuid-ctx, got %s", tid)
+	}
+
+	Ctx(ctx).KV("k", "v").Info("ctx1")
+	Ctx(ctx).Info("ctx2")
+
+	if tid, _ := ctx.Value(LegacyTraceKey).(string); tid != "uuid-ctx" {
+		t.Fatalf("legacy key should be set, got %q", tid)
+	}
+
+	legacy := context.WithValue(context.Background(), LegacyTraceKey, "uuid-legacy")
+	if tid := TraceID(legacy); tid != "uuid-legacy" {
+		t.Fatalf("want uuid-legacy, got %s", tid)
+	}
+
+	l := Trace("a").Trace("b").WithTraceParent(NewTraceParent())
+	tp := NewTraceParent()
+	l = l.WithTraceParent(tp)
+	if len(l.kv) != len(logger.kv)+6 || l.TraceID() != "b" {
+		t.Fatalf("trace fields should be replaced, got %v", l.kv)
+	}
+	for i := 0; i < len(l.kv); i += 2 {
+		if l.kv[i] == "trace_id" && l.kv[i+1] != tp.TraceID {
+			t.Fatalf("want trace_id %s, got %v", tp.TraceID, l.kv[i+1])
+		}
+	}
+
+	if tid := NewTraceID(); len(tid) != 32 {
+		t.Fatalf("bad tid %s", tid)
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	for _, v := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceParent(v); ok {
+			t.Fatalf("%s should be invalid", v)
+		}
+	}
+
+	v := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tp, ok := ParseTraceParent(v)
+	if !ok || !tp.Sampled() || tp.String() != v {
+		t.Fatalf("bad trace parent %+v", tp)
+	}
+
+	l := FromRequest("", v)
+	if l.TraceID() != tp.TraceID || l.TraceParent().TraceID != tp.TraceID || l.TraceParent().SpanID == tp.SpanID {
+		t.Fatalf("bad logger trace %s %+v", l.TraceID(), l.TraceParent())
+	}
+
+	ctx := WithContext(context.Background(), FromRequest("req-1", ""))
+	if got, ok := TraceParentFromContext(ctx); !ok || TraceID(ctx) != "req-1" {
+		t.Fatalf("bad ctx trace %+v", got)
+	}
+	Ctx(ctx).Info("traceparent")
+}
+
+func TestNamed(t *testing.T) {
+	defer SetGlobalLevel(GlobalLevel())
+	SetGlobalLevel(InfoLevel)
+
+	mysql := Named("mysql")
+	if mysql.DebugEnabled() {
+		t.Fatal("mysql should follow global level")
+	}
+
+	if err := SetLevel("[", DebugLevel); err == nil {
+		t.Fatal("want bad pattern err")
+	}
+
+	SetLevel("my*", WarnLevel)
+	SetLevel("mysql", DebugLevel)
+	defer UnsetLevel("my*")
+
+	if !mysql.DebugEnabled() || NamedLevel("myredis") != WarnLevel || NamedLevel("pg") != InfoLevel {
+		t.Fatal("bad named level")
+	}
+	mysql.Debug("named shown")
+	Named("myredis").Info("named hidden")
+
+	levels.RLock()
+	_, recorded := levels.named["pg"]
+	levels.RUnlock()
+	if recorded {
+		t.Fatal("NamedLevel should not record name")
+	}
+
+	UnsetLevel("mysql")
+	if mysql.GetLevel() != WarnLevel {
+		t.Fatal("mysql should match glob")
+	}
+
+	if DebugEnabled() {
+		t.Fatal("global level should not be changed")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	defer SetFormat(GetFormat())
+
+	SetFormat(FormatJSON)
+	if zerolog.TimeFieldFormat != time.RFC3339Nano {
+		t.Fatal("ts should be RFC3339Nano")
+	}
+
+	ts := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	if s := consoleTimeFormatter(ts.Format(time.RFC3339Nano)); s != ts.Local().Format(time.RFC3339) {
+		t.Fatalf("bad console ts %s", s)
+	}
+
+	buf := &bytes.Buffer{}
+	w := LogfmtWriter(buf)
+	_, err := w.Write([]byte(`{"level":"info","tid":"abc","ok":true,"n":1.5,"kv":{"a":1},"ts":"2019-01-01T00:00:00Z","msg":"hello world"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `ts=2019-01-01T00:00:00Z level=info msg="hello world" kv="{\"a\":1}" n=1.5 ok=true tid=abc` + "\n"
+	if buf.String() != want {
+		t.Fatalf("want %s, got %s", want, buf.String())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	if b := RedactJSON([]byte(`{"password":"x"}`)); string(b) != `{"password":"x"}` {
+		t.Fatalf("redaction should be disabled, got %s", b)
+	}
+
+	SetRedact(DefaultRedactConfig)
+	defer DisableRedact()
+
+	b := RedactJSON([]byte(`{"user":{"Password":"123","phone":"13812345678"},"cards":["4111 1111 1111 1111"],"n":1}`))
+	want := `{"cards":["******"],"n":1,"user":{"Password":"******","phone":"******"}}`
+	if string(b) != want {
+		t.Fatalf("want %s, got %s", want, b)
+	}
+
+	if s := RedactString("call 13812345678 now"); s != "call ****** now" {
+		t.Fatalf("bad redact string %s", s)
+	}
+
+	// not a card number by Luhn check
+	if s := RedactString("order 1234567812345678"); s != "order 1234567812345678" {
+		t.Fatalf("id should be kept: %s", s)
+	}
+
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+	defer SetOutput(stdWriter(GetFormat()))
+
+	KV("authorization", "Bearer x").KV("req", map[string]string{"token": "abc", "name": "n"}).
+		Infof("login %s", "13812345678")
+
+	out := buf.String()
+	for _, v := range []string{"Bearer", "abc", "13812345678"} {
+		if strings.Contains(out, v) {
+			t.Fatalf("%s should be masked: %s", v, out)
+		}
+	}
+	if !strings.Contains(out, `"name":"n"`) {
+		t.Fatalf("name should be kept: %s", out)
+	}
+}
+
 func TestCaller(t *testing.T) {
 	Caller().Info("caller1")
 	KV("x", "y").Caller().Errorf("caller2")
@@ -127,6 +315,83 @@ func TestSample(t *testing.T) {
 	sLog.KV("k", "v").Debugf("shown6")
 }
 
+func TestTokenBucketSampler(t *testing.T) {
+	s := &TokenBucketSampler{Rate: 1, Burst: 2}
+
+	n := 0
+	for i := 0; i < 10; i++ {
+		if s.Sample(ErrorLevel) {
+			n++
+		}
+	}
+
+	if n != 2 {
+		t.Fatalf("want 2 passed, got %d", n)
+	}
+}
+
+func TestDedupSampler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mu := sync.Mutex{}
+	SetOutput(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}))
+	defer SetOutput(stdWriter(GetFormat()))
+
+	samplers := []Sampler{
+		&DedupSampler{Window: 50 * time.Millisecond},
+		LevelSampler{ErrorSampler: &DedupSampler{Window: 50 * time.Millisecond}},
+	}
+	for _, sampler := range samplers {
+		l := Sample(sampler)
+		for i := 0; i < 5; i++ {
+			l.Errorf("db down: %s", "query a")
+		}
+		// different message is not collapsed
+		l.Errorf("db down: %s", "query b")
+
+		// repeated count is flushed when window ends
+		time.Sleep(100 * time.Millisecond)
+
+		mu.Lock()
+		var lines []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.Contains(line, "db down") {
+				lines = append(lines, line)
+			}
+		}
+		buf.Reset()
+		mu.Unlock()
+
+		if len(lines) != 3 {
+			t.Fatalf("%T: want 3 lines, got %q", sampler, lines)
+		}
+		if !strings.Contains(lines[2], "query a") || !strings.Contains(lines[2], `"repeated":4`) {
+			t.Fatalf("%T: want repeated 4 of query a, got %s", sampler, lines[2])
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("nested DedupSampler should panic")
+			}
+		}()
+		Sample(&BurstSampler{Burst: 1, NextSampler: &DedupSampler{}})
+	}()
+
+	SetSampler(&BurstSampler{Burst: 1, Period: time.Minute})
+	defer SetSampler(nil)
+
+	Error("burst1")
+	Error("burst2")
+	if strings.Count(buf.String(), "burst") != 1 {
+		t.Fatalf("global sampler should be used, got %s", buf.String())
+	}
+}
+
 func TestWithStack(t *testing.T) {
 	WithStack().Debug("hello")
 	KV("stack", "x").WithStack().Debug("hello")
@@ -145,6 +410,454 @@ func TestLog2Redis(t *testing.T) {
 	WithStack().Info("stack")
 }
 
+func TestFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "app.log")
+
+	fw := FileWriter(FileConfig{
+		Filename:   name,
+		Level:      InfoLevel,
+		MaxBackups: 2,
+		Compress:   true,
+	}).(*fileWriter)
+
+	fw.WriteLevel(DebugLevel, []byte("hidden\n"))
+
+	for i := 0; i < 4; i++ {
+		fw.WriteLevel(InfoLevel, []byte(fmt.Sprintf("line%d\n", i)))
+		// rotated within a millisecond, backups get sequence
+		if err := fw.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fw.WriteLevel(InfoLevel, []byte("current\n"))
+
+	// file moved by logrotate
+	os.Rename(name, name+".1")
+	if err := fw.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("reopened\n"))
+
+	// reopen fails, the old file is kept
+	os.Rename(name, name+".2")
+	os.Mkdir(name, 0755)
+	if err := fw.Reopen(); err == nil {
+		t.Fatal("want reopen err")
+	}
+	if _, err := fw.Write([]byte("kept\n")); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(name)
+	os.Rename(name+".2", name)
+
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fw.Write([]byte("closed\n")); err == nil {
+		t.Fatal("want err after close")
+	}
+
+	backups, err := fw.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("want 2 backups, got %v", backups)
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b, ".gz") {
+			t.Fatalf("want compressed backup, got %s", b)
+		}
+	}
+
+	if b, _ := ioutil.ReadFile(name + ".1"); string(b) != "current\n" {
+		t.Fatalf("bad moved file %q", b)
+	}
+	if b, _ := ioutil.ReadFile(name); string(b) != "reopened\nkept\n" {
+		t.Fatalf("bad reopened file %q", b)
+	}
+}
+
+func TestConcurrentSet(t *testing.T) {
+	defer SetOutput(stdWriter(GetFormat()))
+	defer SetCallerEnable(false)
+
+	buf := &bytes.Buffer{}
+	mu := sync.Mutex{}
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				KV("j", j).Info("concurrent")
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		SetOutput(w)
+		SetAttachment(map[string]interface{}{"round": i})
+		SetCallDepth(2)
+		SetCallerEnable(i%2 == 0)
+	}
+
+	wg.Wait()
+}
+
+func TestAsyncPolicy(t *testing.T) {
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var got []string
+	w := writerFunc(func(p []byte) (int, error) {
+		<-block
+		mu.Lock()
+		got = append(got, string(p))
+		mu.Unlock()
+		return len(p), nil
+	})
+
+	wr := NewAsyncWriterWithConfig(w, AsyncConfig{Name: "test", Size: 2, Policy: DropNewest})
+	for i := 0; i < 5; i++ {
+		wr.WriteLevel(InfoLevel, []byte(fmt.Sprint(i)))
+		if i == 0 {
+			// wait poller to take it
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 1 in poller, 2 in queue
+	if st := AsyncWriterStats()["test"]; st.Dropped != 2 || st.Queued != 2 {
+		t.Fatalf("bad stats %+v", st)
+	}
+
+	close(block)
+	wr.Close()
+
+	if st := wr.Stats(); st.Written != 3 || st.Queued != 0 {
+		t.Fatalf("bad stats %+v", st)
+	}
+	if _, ok := AsyncWriterStats()["test"]; ok {
+		t.Fatal("closed writer should be unregistered")
+	}
+
+	// error bypasses queue
+	got = nil
+	wr = NewAsyncWriterWithConfig(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		got = append(got, string(p))
+		mu.Unlock()
+		return len(p), nil
+	}), AsyncConfig{Policy: NeverDropErrors, PollInterval: time.Hour})
+	wr.WriteLevel(ErrorLevel, []byte("err"))
+
+	mu.Lock()
+	if len(got) != 1 || got[0] != "err" {
+		t.Fatalf("error should be written directly, got %v", got)
+	}
+	mu.Unlock()
+	wr.Close()
+
+	// block times out
+	wr = NewAsyncWriterWithConfig(writerFunc(func(p []byte) (int, error) {
+		time.Sleep(50 * time.Millisecond)
+		return len(p), nil
+	}), AsyncConfig{Size: 1, Policy: Block, BlockTimeout: 5 * time.Millisecond})
+	for i := 0; i < 4; i++ {
+		wr.Write([]byte("x"))
+	}
+	if st := wr.Stats(); st.Dropped == 0 {
+		t.Fatalf("block should drop on timeout %+v", st)
+	}
+	wr.Close()
+}
+
+// fakeRedis records commands and replies OK, it's enough for pipelines of RPUSH, LTRIM, XADD.
+func fakeRedis(t *testing.T) (addr string, cmds func() [][]string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var got [][]string
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					// *N\r\n then N of $len\r\nvalue\r\n
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					cmd := make([]string, n)
+					for i := range cmd {
+						r.ReadString('\n')
+						v, _ := r.ReadString('\n')
+						cmd[i] = strings.TrimSuffix(v, "\r\n")
+					}
+					mu.Lock()
+					got = append(got, cmd)
+					mu.Unlock()
+					conn.Write([]byte("+OK\r\n"))
+				}
+			}()
+		}
+	}()
+
+	t.Cleanup(func() { ln.Close() })
+
+	return ln.Addr().String(), func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]string(nil), got...)
+	}
+}
+
+func TestRedisWriterBatch(t *testing.T) {
+	addr, cmds := fakeRedis(t)
+
+	w := RedisWriter(RedisConfig{
+		DSN:           "redis://" + addr,
+		LogKey:        "log:test",
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		MaxLen:        100,
+	}).(*redisWriter)
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte(fmt.Sprint(i)))
+	}
+	time.Sleep(50 * time.Millisecond)
+	w.Close()
+
+	want := [][]string{
+		{"rpush", "log:test", "0", "1", "2"},
+		{"ltrim", "log:test", "-100", "-1"},
+		{"rpush", "log:test", "3"},
+		{"ltrim", "log:test", "-100", "-1"},
+	}
+	if got := cmds(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestRedisWriterSpill(t *testing.T) {
+	var mu sync.Mutex
+	spilled := 0
+
+	// nothing listens on it
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	ln.Close()
+
+	w := RedisWriter(RedisConfig{
+		DSN:           "redis://" + ln.Addr().String(),
+		BatchSize:     2,
+		MaxPending:    2,
+		FlushInterval: time.Hour,
+		Stream:        true,
+		Spill: writerFunc(func(p []byte) (int, error) {
+			mu.Lock()
+			spilled++
+			mu.Unlock()
+			return len(p), nil
+		}),
+	}).(*redisWriter)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		w.Write([]byte("x"))
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("write should not block")
+	}
+	w.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if spilled != 10 {
+		t.Fatalf("want 10 spilled, got %d", spilled)
+	}
+}
+
+func TestNetWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := NetWriter(NetConfig{
+		Level:   InfoLevel,
+		Addr:    addr,
+		Framing: FramingOctetCounting,
+	}).(*netWriter)
+	defer w.Close()
+
+	// nothing listens, it drops lines and backs off after dial err
+	waitDialed(w)
+	dropped := NetWriterDropped()["net"]
+	for i := 0; i < 2; i++ {
+		if n, err := w.Write([]byte("lost\n")); n != 5 || err != nil {
+			t.Fatalf("want dropped silently, got %d %v", n, err)
+		}
+	}
+	if err := w.send([]byte("lost\n")); err != errNetBackoff {
+		t.Fatalf("want backoff err, got %v", err)
+	}
+	if d := NetWriterDropped()["net"] - dropped; d != 3 {
+		t.Fatalf("want 3 dropped, got %v", d)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w.mu.Lock()
+	w.retryAt = time.Time{}
+	w.mu.Unlock()
+
+	// dial in background, the line is dropped
+	start := time.Now()
+	w.Write([]byte("dialing\n"))
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("write should not wait for dial")
+	}
+	waitDialed(w)
+
+	w.WriteLevel(DebugLevel, []byte("filtered\n"))
+	if _, err := w.WriteLevel(InfoLevel, []byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("world"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	buf := make([]byte, len("5 hello5 world"))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "5 hello5 world" {
+		t.Fatalf("unexpected frames %q", buf)
+	}
+}
+
+// waitDialed waits for background dial of nw.
+func waitDialed(nw *netWriter) {
+	for i := 0; i < 100; i++ {
+		nw.mu.Lock()
+		dialing := nw.dialing
+		nw.mu.Unlock()
+		if !dialing {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyslogWriter(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w := SyslogWriter(SyslogConfig{
+		Level:    InfoLevel,
+		Addr:     pc.LocalAddr().String(),
+		AppName:  "my app",
+		Hostname: "host",
+	}).(*syslogWriter)
+	defer w.Close()
+	waitDialed(w.nw)
+
+	w.WriteLevel(DebugLevel, []byte("filtered\n"))
+	w.WriteLevel(InfoLevel, []byte("{\"msg\":\"hi\"}\n"))
+	w.WriteLevel(ErrorLevel, []byte("oops\n"))
+
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+
+	var msgs []string
+	for i := 0; i < 2; i++ {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, string(buf[:n]))
+	}
+
+	suffix := " host my_app " + strconv.Itoa(os.Getpid()) + " - - {\"msg\":\"hi\"}"
+	if !strings.HasPrefix(msgs[0], "<14>1 ") || !strings.HasSuffix(msgs[0], suffix) {
+		t.Fatalf("unexpected syslog msg %q", msgs[0])
+	}
+	if !strings.HasPrefix(msgs[1], "<11>1 ") || !strings.HasSuffix(msgs[1], " - - oops") {
+		t.Fatalf("unexpected syslog msg %q", msgs[1])
+	}
+}
+
+func TestSyslogWriterAsync(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	for _, policy := range []Policy{DropOldest, Block} {
+		w := SyslogWriter(SyslogConfig{
+			Level:    InfoLevel,
+			Addr:     pc.LocalAddr().String(),
+			Hostname: "host",
+			Async:    true,
+			Policy:   policy,
+		}).(Writer)
+		waitDialed(w.w.(*syslogWriter).nw)
+
+		w.WriteLevel(WarnLevel, []byte("careful\n"))
+		w.Close()
+
+		pc.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg := string(buf[:n]); !strings.HasPrefix(msg, "<12>1 ") {
+			t.Fatalf("policy %d: unexpected syslog msg %q", policy, msg)
+		}
+	}
+}
+
 func TestSetGlobalLevel(t *testing.T) {
 	SetGlobalLevel(InfoLevel)
 }
@@ -193,3 +906,9 @@ func BenchmarkKV(b *testing.B) {
 		KV("a", "b").KV("x", "y").Debugf("hello, %s", "world")
 	}
 }
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,323 @@
+// Package logtest captures logs in tests.
+//
+//	func TestX(t *testing.T) {
+//	    rec := logtest.Capture(t)
+//	    doSomething(rec.Context(context.Background()))
+//	    logtest.AssertLogged(t, log.ErrorLevel, "db down", "span", "sql")
+//	    _ = rec.Entries()
+//	}
+//
+// Logs written by rec.Log or the logger of rec.Context are tagged, only rec sees them.
+// Untagged logs are seen by every capture active at the time, so parallel tests
+// asserting on logs should log through the tagged logger.
+//
+// Logs are still written to previous output, which is restored when the last capture
+// is cleaned up. Lines that are not json, e.g. log format is not JSON, fail the tests.
+package logtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// TagField is field name of capture tag, it's removed from Entry.Fields.
+const TagField = "logtest"
+
+// Entry is parsed log entry.
+type Entry struct {
+	Level  log.Level
+	Msg    string
+	Caller string
+	Time   time.Time
+	Fields map[string]interface{} // fields except level, msg, caller, ts
+	Raw    string
+}
+
+// Field returns field value as string, json numbers and objects are formatted by fmt.
+func (e Entry) Field(k string) (string, bool) {
+	v, ok := e.Fields[k]
+	if !ok {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(v), true
+}
+
+// Recorder records logs.
+type Recorder struct {
+	t   testing.TB
+	tag string
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Log returns logger whose logs are recorded by r only.
+func (r *Recorder) Log() log.Log {
+	return log.KV(TagField, r.tag)
+}
+
+// Context returns a copy of ctx with r.Log attached.
+func (r *Recorder) Context(ctx context.Context) context.Context {
+	return log.WithContext(ctx, r.Log())
+}
+
+// Entries returns entries recorded.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Reset drops entries recorded.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// Find returns entries of level containing msg and matching fields kv pairs.
+func (r *Recorder) Find(level log.Level, msg string, fields ...interface{}) []Entry {
+	var found []Entry
+	for _, e := range r.Entries() {
+		if e.Level == level && strings.Contains(e.Msg, msg) && matchFields(e, fields) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged fails t if no entry of level containing msg and matching fields kv pairs.
+func (r *Recorder) AssertLogged(t testing.TB, level log.Level, msg string, fields ...interface{}) {
+	t.Helper()
+	if len(r.Find(level, msg, fields...)) == 0 {
+		t.Errorf("no %s log contains %q with fields %v, got:\n%s", level, msg, fields, r.dump())
+	}
+}
+
+// AssertNotLogged fails t if any entry of level containing msg and matching fields kv pairs.
+func (r *Recorder) AssertNotLogged(t testing.TB, level log.Level, msg string, fields ...interface{}) {
+	t.Helper()
+	if found := r.Find(level, msg, fields...); len(found) != 0 {
+		t.Errorf("unexpected %s log contains %q with fields %v: %s", level, msg, fields, found[0].Raw)
+	}
+}
+
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		b.WriteString(e.Raw)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (r *Recorder) add(e Entry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+// hub is the shared capture writer.
+var hub = struct {
+	sync.RWMutex
+	prev io.Writer
+	recs map[string]*Recorder // by tag
+	byT  map[testing.TB]*Recorder
+	seq  uint64
+}{
+	recs: map[string]*Recorder{},
+	byT:  map[testing.TB]*Recorder{},
+}
+
+type hubWriter struct{}
+
+// Write write data to writer and previous output
+func (hubWriter) Write(p []byte) (n int, err error) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	if hub.prev != nil {
+		hub.prev.Write(p)
+	}
+	return record(p)
+}
+
+// WriteLevel write data to writer and previous output
+func (hubWriter) WriteLevel(level log.Level, p []byte) (n int, err error) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	if lw, ok := hub.prev.(zerolog.LevelWriter); ok {
+		lw.WriteLevel(level, p)
+	} else if hub.prev != nil {
+		hub.prev.Write(p)
+	}
+	return record(p)
+}
+
+// record adds p to recorders, hub should be locked.
+func record(p []byte) (n int, err error) {
+	e, err := parse(p)
+	if err != nil {
+		for _, r := range hub.recs {
+			r.t.Errorf("logtest: unparseable log %q: %v", bytes.TrimSpace(p), err)
+		}
+		return 0, err
+	}
+
+	if tag, ok := e.Fields[TagField].(string); ok {
+		delete(e.Fields, TagField)
+		if r, ok := hub.recs[tag]; ok {
+			r.add(e)
+		}
+		return len(p), nil
+	}
+
+	for _, r := range hub.recs {
+		r.add(e)
+	}
+	return len(p), nil
+}
+
+// Capture starts capturing logs, it stops on t cleanup.
+func Capture(t testing.TB) *Recorder {
+	r := &Recorder{
+		t:   t,
+		tag: strconv.FormatUint(atomic.AddUint64(&hub.seq, 1), 10),
+	}
+
+	hub.Lock()
+	if len(hub.recs) == 0 {
+		hub.prev = log.Output()
+		log.SetOutput(hubWriter{})
+	}
+	hub.recs[r.tag] = r
+	hub.byT[t] = r
+	hub.Unlock()
+
+	t.Cleanup(func() {
+		hub.Lock()
+		defer hub.Unlock()
+
+		delete(hub.recs, r.tag)
+		if hub.byT[t] == r {
+			delete(hub.byT, t)
+		}
+
+		if len(hub.recs) == 0 && hub.prev != nil {
+			log.SetOutput(hub.prev)
+			hub.prev = nil
+		}
+	})
+
+	return r
+}
+
+// AssertLogged fails t if no entry of level containing msg and matching fields kv pairs
+// was captured by Capture(t).
+func AssertLogged(t testing.TB, level log.Level, msg string, fields ...interface{}) {
+	t.Helper()
+	recorder(t).AssertLogged(t, level, msg, fields...)
+}
+
+// AssertNotLogged fails t if any entry of level containing msg and matching fields kv pairs
+// was captured by Capture(t).
+func AssertNotLogged(t testing.TB, level log.Level, msg string, fields ...interface{}) {
+	t.Helper()
+	recorder(t).AssertNotLogged(t, level, msg, fields...)
+}
+
+func recorder(t testing.TB) *Recorder {
+	t.Helper()
+
+	hub.RLock()
+	r, ok := hub.byT[t]
+	hub.RUnlock()
+
+	if !ok {
+		t.Fatal("logtest.Capture(t) should be called first")
+	}
+	return r
+}
+
+func matchFields(e Entry, fields []interface{}) bool {
+	for i := 0; i+1 < len(fields); i += 2 {
+		k := fmt.Sprint(fields[i])
+
+		var got string
+		var ok bool
+		switch k {
+		case "caller":
+			got, ok = e.Caller, e.Caller != ""
+		default:
+			got, ok = e.Field(k)
+		}
+
+		if !ok || got != fmt.Sprint(fields[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func parse(p []byte) (Entry, error) {
+	m := map[string]interface{}{}
+
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{
+		Raw:    string(bytes.TrimSpace(p)),
+		Fields: m,
+	}
+
+	if v, ok := m[zerolog.LevelFieldName].(string); ok {
+		e.Level, _ = zerolog.ParseLevel(v)
+		delete(m, zerolog.LevelFieldName)
+	}
+
+	if v, ok := m[zerolog.MessageFieldName].(string); ok {
+		e.Msg = v
+		delete(m, zerolog.MessageFieldName)
+	}
+
+	if v, ok := m["caller"].(string); ok {
+		e.Caller = v
+		delete(m, "caller")
+	}
+
+	switch v := m[zerolog.TimestampFieldName].(type) {
+	case string:
+		e.Time, _ = time.Parse(time.RFC3339Nano, v)
+		delete(m, zerolog.TimestampFieldName)
+	case json.Number:
+		if sec, err := v.Int64(); err == nil {
+			e.Time = time.Unix(sec, 0)
+		}
+		delete(m, zerolog.TimestampFieldName)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,65 @@
+package logtest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestCapture(t *testing.T) {
+	prev := &syncBuffer{}
+	log.SetOutput(prev)
+
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"a", "b"} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				rec := Capture(t)
+				log.Ctx(rec.Context(context.Background())).KV("sub", name).KV("n", 1).Caller().Errorf("hello %s", name)
+
+				AssertLogged(t, log.ErrorLevel, "hello "+name, "sub", name, "n", 1)
+				AssertNotLogged(t, log.InfoLevel, "hello "+name)
+				AssertNotLogged(t, log.ErrorLevel, "hello", "sub", map[string]string{"a": "b", "b": "a"}[name])
+
+				e := rec.Find(log.ErrorLevel, "hello "+name)[0]
+				if e.Caller == "" || e.Time.IsZero() {
+					t.Errorf("bad entry %+v", e)
+				}
+				if _, ok := e.Fields[TagField]; ok {
+					t.Errorf("tag should be removed, got %+v", e)
+				}
+			})
+		}
+	})
+
+	if log.Output() != prev {
+		t.Fatal("output should be restored")
+	}
+
+	if out := prev.String(); !strings.Contains(out, "hello a") || !strings.Contains(out, "hello b") {
+		t.Fatalf("logs should be written to previous output, got %q", out)
+	}
+}
@@ -0,0 +1,345 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Framing is message framing of stream network, see RFC6587.
+type Framing int
+
+// Framing
+const (
+	FramingNewline       Framing = iota // message ends with "\n"
+	FramingOctetCounting                // "len SP message"
+)
+
+// NetConfig is conf for network writer.
+type NetConfig struct {
+	Level   Level
+	Network string // tcp, udp or unix, default tcp
+	Addr    string
+	Framing Framing       // ignored by datagram network, each message is a datagram
+	Timeout time.Duration // dial and write timeout, default 3s
+	Async   bool
+	Policy  Policy // backpressure policy if Async
+}
+
+const (
+	netMinBackoff = 100 * time.Millisecond
+	netMaxBackoff = 30 * time.Second
+)
+
+var (
+	errNetBackoff   = errors.New("log net writer is waiting to connect")
+	errWriterClosed = errors.New("log writer closed")
+)
+
+// netWriter writes framed messages to conn, it reconnects with backoff on errors.
+// Dial runs in background, so logging is not blocked by unreachable addr.
+type netWriter struct {
+	name string // name in NetWriterDropped
+	conf NetConfig
+
+	mu      sync.Mutex
+	conn    net.Conn
+	closed  bool
+	dialing bool
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// NetWriter network writer, messages are dropped while it's reconnecting, dropped ones
+// are counted in NetWriterDropped.
+func NetWriter(conf NetConfig) io.Writer {
+	return asyncOrSync("net", conf.Async, conf.Level, conf.Policy, newNetWriter("net", conf))
+}
+
+func newNetWriter(name string, conf NetConfig) *netWriter {
+	if conf.Network == "" {
+		conf.Network = "tcp"
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 3 * time.Second
+	}
+
+	nw := &netWriter{name: name, conf: conf, dialing: true}
+	go nw.dial()
+	return nw
+}
+
+// dial connects addr, nw.dialing must be set.
+func (nw *netWriter) dial() {
+	conn, err := net.DialTimeout(nw.conf.Network, nw.conf.Addr, nw.conf.Timeout)
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	nw.dialing = false
+	switch {
+	case err != nil:
+		nw.fail()
+	case nw.closed:
+		conn.Close()
+	default:
+		nw.conn = conn
+	}
+}
+
+// asyncOrSync wraps w by async writer if async, w is closed by Close.
+func asyncOrSync(name string, async bool, lv Level, policy Policy, w io.WriteCloser) io.Writer {
+	if async {
+		wr := NewAsyncWriterWithConfig(w, AsyncConfig{
+			Name:   name,
+			Level:  lv,
+			Policy: policy,
+			Alerter: func(missed int) {
+				log.Printf("%s writer dropped %d messages", name, missed)
+			},
+		})
+
+		// Close of async writer closes w
+		addCloser(wr.Close)
+
+		return wr
+	}
+
+	addCloser(w.Close)
+
+	return w
+}
+
+// Write write data to writer, p is dropped without error if conn is down, so other
+// writers of multi writer still get it.
+func (nw *netWriter) Write(p []byte) (n int, err error) {
+	nw.send(p)
+	return len(p), nil
+}
+
+// WriteLevel write data to writer with level info provided
+func (nw *netWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if level < nw.conf.Level {
+		return len(p), nil
+	}
+
+	return nw.Write(p)
+}
+
+// Close closes conn.
+func (nw *netWriter) Close() error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	nw.closed = true
+	if nw.conn == nil {
+		return nil
+	}
+
+	err := nw.conn.Close()
+	nw.conn = nil
+	return err
+}
+
+func (nw *netWriter) datagram() bool {
+	switch nw.conf.Network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+	return false
+}
+
+// send writes msg with framing, msg is counted as dropped on error.
+func (nw *netWriter) send(msg []byte) (err error) {
+	defer func() {
+		if err != nil {
+			addNetDropped(nw.name)
+		}
+	}()
+
+	msg = bytes.TrimRight(msg, "\n")
+
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	if nw.closed {
+		return errWriterClosed
+	}
+
+	if nw.conn == nil {
+		// msg is dropped until connected
+		if !nw.dialing && !time.Now().Before(nw.retryAt) {
+			nw.dialing = true
+			go nw.dial()
+		}
+		return errNetBackoff
+	}
+
+	var frame []byte
+	switch {
+	case nw.datagram():
+		frame = msg
+	case nw.conf.Framing == FramingOctetCounting:
+		frame = strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+		frame = append(frame, ' ')
+		frame = append(frame, msg...)
+	default:
+		frame = append(append(make([]byte, 0, len(msg)+1), msg...), '\n')
+	}
+
+	nw.conn.SetWriteDeadline(time.Now().Add(nw.conf.Timeout))
+	if _, err := nw.conn.Write(frame); err != nil {
+		nw.conn.Close()
+		nw.conn = nil
+		nw.fail()
+		return err
+	}
+
+	nw.backoff = 0
+	return nil
+}
+
+// fail sets backoff of next dial, nw.mu must be held.
+func (nw *netWriter) fail() {
+	if nw.backoff == 0 {
+		nw.backoff = netMinBackoff
+	} else if nw.backoff *= 2; nw.backoff > netMaxBackoff {
+		nw.backoff = netMaxBackoff
+	}
+	nw.retryAt = time.Now().Add(nw.backoff)
+}
+
+// SyslogConfig is conf for syslog writer.
+type SyslogConfig struct {
+	Level    Level
+	Network  string // tcp, udp or unix, default udp
+	Addr     string // default 127.0.0.1:514
+	Framing  Framing
+	Timeout  time.Duration // dial and write timeout, default 3s
+	Facility int           // default 1 (user)
+	AppName  string        // default base name of executable
+	Hostname string        // default os.Hostname()
+	Async    bool
+	Policy   Policy // backpressure policy if Async
+}
+
+// syslogWriter writes RFC5424 messages.
+type syslogWriter struct {
+	nw       *netWriter
+	level    Level
+	facility int
+	header   string // " hostname appname procid - - " after timestamp
+}
+
+// SyslogWriter syslog writer in RFC5424 format, the log line is the MSG part.
+func SyslogWriter(conf SyslogConfig) io.Writer {
+	if conf.Network == "" {
+		conf.Network = "udp"
+	}
+
+	if conf.Addr == "" {
+		conf.Addr = "127.0.0.1:514"
+	}
+
+	if conf.Facility <= 0 {
+		conf.Facility = 1
+	}
+
+	if conf.AppName == "" {
+		conf.AppName = filepath.Base(os.Args[0])
+	}
+
+	if conf.Hostname == "" {
+		conf.Hostname, _ = os.Hostname()
+	}
+
+	sw := &syslogWriter{
+		nw: newNetWriter("syslog", NetConfig{
+			Network: conf.Network,
+			Addr:    conf.Addr,
+			Framing: conf.Framing,
+			Timeout: conf.Timeout,
+		}),
+		level:    conf.Level,
+		facility: conf.Facility,
+		header: " " + syslogField(conf.Hostname, 255) + " " + syslogField(conf.AppName, 48) +
+			" " + strconv.Itoa(os.Getpid()) + " - - ",
+	}
+
+	return asyncOrSync("syslog", conf.Async, conf.Level, conf.Policy, sw)
+}
+
+// Write write data to writer, severity is informational.
+func (sw *syslogWriter) Write(p []byte) (n int, err error) {
+	return sw.WriteLevel(InfoLevel, p)
+}
+
+// WriteLevel write data to writer with level info provided
+func (sw *syslogWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if level < sw.level {
+		return len(p), nil
+	}
+
+	pri := sw.facility*8 + syslogSeverity(level)
+
+	msg := make([]byte, 0, len(p)+len(sw.header)+48)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(pri), 10)
+	msg = append(msg, ">1 "...)
+	msg = time.Now().AppendFormat(msg, time.RFC3339Nano)
+	msg = append(msg, sw.header...)
+	msg = append(msg, p...)
+
+	// dropped if conn is down, see netWriter.Write
+	sw.nw.send(msg)
+	return len(p), nil
+}
+
+// Close closes conn.
+func (sw *syslogWriter) Close() error {
+	return sw.nw.Close()
+}
+
+func syslogSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarnLevel:
+		return 4
+	case ErrorLevel:
+		return 3
+	case FatalLevel:
+		return 2
+	default:
+		return 5 // notice
+	}
+}
+
+// syslogField returns "-" for empty value, value is truncated to max and
+// non printable chars are replaced by "_".
+func syslogField(v string, max int) string {
+	if v == "" {
+		return "-"
+	}
+
+	b := []byte(v)
+	if len(b) > max {
+		b = b[:max]
+	}
+	for i := range b {
+		if b[i] < 33 || b[i] > 126 {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,213 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactConfig is conf for redaction of sensitive fields.
+type RedactConfig struct {
+	Keys     []string         // values of keys containing any of them are masked, case-insensitive
+	Patterns []*regexp.Regexp // matched parts of values and messages are masked
+	Mask     string           // default is "******"
+}
+
+// common sensitive patterns, matches of RedactCardNumber failing Luhn check are kept,
+// so ids of 16 or 19 digits are mostly not masked.
+var (
+	RedactPhone      = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+	RedactCardNumber = regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{4}(?:\d{3})?\b`)
+)
+
+// patternChecks validates matches of patterns, only valid ones are masked.
+var patternChecks = map[*regexp.Regexp]func(string) bool{
+	RedactCardNumber: luhn,
+}
+
+// DefaultRedactConfig is default conf of redaction.
+var DefaultRedactConfig = RedactConfig{
+	Keys: []string{
+		"password", "passwd", "pwd", "secret", "token", "authorization", "cookie",
+		"credential", "private_key", "card_no", "id_card",
+	},
+	Patterns: []*regexp.Regexp{RedactPhone, RedactCardNumber},
+}
+
+const defaultRedactMask = "******"
+
+type redactor struct {
+	keys     []string
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+var gRedactor atomic.Value // *redactor
+
+func loadRedactor() *redactor {
+	r, _ := gRedactor.Load().(*redactor)
+	return r
+}
+
+// SetRedact enables redaction of kv values, messages and RedactJSON before anything
+// reaches writers.
+func SetRedact(conf RedactConfig) {
+	r := &redactor{
+		patterns: conf.Patterns,
+		mask:     conf.Mask,
+	}
+
+	if r.mask == "" {
+		r.mask = defaultRedactMask
+	}
+
+	for _, k := range conf.Keys {
+		if k != "" {
+			r.keys = append(r.keys, strings.ToLower(k))
+		}
+	}
+
+	gRedactor.Store(r)
+}
+
+// DisableRedact disables redaction.
+func DisableRedact() {
+	gRedactor.Store((*redactor)(nil))
+}
+
+// RedactString masks parts of s matching patterns.
+func RedactString(s string) string {
+	if r := loadRedactor(); r != nil {
+		return r.str(s)
+	}
+	return s
+}
+
+// RedactJSON masks values of sensitive keys and parts matching patterns in json data,
+// data is returned as it is if redaction is disabled.
+func RedactJSON(data []byte) []byte {
+	if r := loadRedactor(); r != nil {
+		return r.json(data)
+	}
+	return data
+}
+
+func (r *redactor) sensitive(k string) bool {
+	if len(r.keys) == 0 {
+		return false
+	}
+
+	k = strings.ToLower(k)
+	for i := range r.keys {
+		if strings.Contains(k, r.keys[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) str(s string) string {
+	for _, p := range r.patterns {
+		check := patternChecks[p]
+		if check == nil {
+			s = p.ReplaceAllLiteralString(s, r.mask)
+			continue
+		}
+
+		s = p.ReplaceAllStringFunc(s, func(m string) string {
+			if check(m) {
+				return r.mask
+			}
+			return m
+		})
+	}
+	return s
+}
+
+// luhn reports whether digits of s pass Luhn check, non-digits are skipped.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n != 0 && sum%10 == 0
+}
+
+func (r *redactor) json(data []byte) []byte {
+	var v interface{}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return []byte(r.str(string(data)))
+	}
+
+	b, err := json.Marshal(r.walk(v))
+	if err != nil {
+		return []byte(r.str(string(data)))
+	}
+	return b
+}
+
+// walk masks decoded json value v.
+func (r *redactor) walk(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case string:
+		return r.str(vv)
+	case map[string]interface{}:
+		for k := range vv {
+			if r.sensitive(k) {
+				vv[k] = r.mask
+			} else {
+				vv[k] = r.walk(vv[k])
+			}
+		}
+	case []interface{}:
+		for i := range vv {
+			vv[i] = r.walk(vv[i])
+		}
+	}
+	return v
+}
+
+// kv add kv to evt with redaction.
+func (r *redactor) kv(evt *Event, k string, v interface{}) {
+	if r.sensitive(k) {
+		evt.Str(k, r.mask)
+		return
+	}
+
+	switch vv := v.(type) {
+	case string:
+		evt.Str(k, r.str(vv))
+	case float64:
+		evt.Float64(k, vv)
+	case int64:
+		evt.Int64(k, vv)
+	case int:
+		evt.Int(k, vv)
+	case error:
+		evt.Str(k, r.str(vv.Error()))
+	default:
+		b, err := json.Marshal(vv)
+		if err != nil {
+			evt.Interface(k, vv)
+			return
+		}
+		evt.RawJSON(k, r.json(b))
+	}
+}
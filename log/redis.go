@@ -3,6 +3,8 @@ package log
 import (
 	"io"
 	"log"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -14,15 +16,45 @@ type RedisConfig struct {
 	DSN    string
 	LogKey string
 	Async  bool
+	Policy Policy // backpressure policy if Async
+
+	BatchSize     int           // lines per flush, default 100
+	FlushInterval time.Duration // default 100ms
+	MaxPending    int           // lines buffered while redis is slow or down, exceeded ones spill, default 10 * BatchSize
+	MaxLen        int64         // trim list or stream to about MaxLen, 0 means unbounded
+	Stream        bool          // XADD to stream LogKey with field "log" instead of RPUSH
+	Spill         io.Writer     // lines can't be written to redis go here, default os.Stderr
+
 	client *redis.Client
 }
 
-// RedisWriter redis writer.
+const (
+	redisMinBackoff = 100 * time.Millisecond
+	redisMaxBackoff = 30 * time.Second
+)
+
+// RedisWriter redis writer, lines are batched and pushed by pipeline, it never blocks on redis.
 func RedisWriter(conf RedisConfig) io.Writer {
 	if conf.LogKey == "" {
 		conf.LogKey = "log:basic"
 	}
 
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 100
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 100 * time.Millisecond
+	}
+
+	if conf.MaxPending <= 0 {
+		conf.MaxPending = 10 * conf.BatchSize
+	}
+
+	if conf.Spill == nil {
+		conf.Spill = os.Stderr
+	}
+
 	opt, err := redis.ParseURL(conf.DSN)
 	if err != nil {
 		panic(err)
@@ -30,33 +62,210 @@ func RedisWriter(conf RedisConfig) io.Writer {
 
 	conf.client = redis.NewClient(opt)
 
-	if conf.Async {
-		wr := NewAsyncWriter(conf.Level, conf, 1000, 10*time.Millisecond, func(missed int) {
-			log.Printf("Redis Writer dropped %d messages", missed)
-		})
+	return asyncOrSync("redis", conf.Async, conf.Level, conf.Policy, newRedisWriter(conf))
+}
 
-		asyncWaitList = append(asyncWaitList, func() error {
-			wr.Close()
-			conf.client.Close()
-			return nil
-		})
+// redisWriter buffers lines and flushes them by pipeline.
+type redisWriter struct {
+	conf RedisConfig
 
-		return wr
-	}
+	mu      sync.Mutex
+	pending [][]byte
+	closed  bool
+
+	flushCh chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
 
-	return conf
+	// only accessed by flush loop
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newRedisWriter(conf RedisConfig) *redisWriter {
+	rw := &redisWriter{
+		conf:    conf,
+		flushCh: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go rw.loop()
+	return rw
 }
 
 // Write write data to writer
-func (c RedisConfig) Write(p []byte) (n int, err error) {
-	return len(p), c.client.RPush(c.LogKey, p).Err()
+func (rw *redisWriter) Write(p []byte) (n int, err error) {
+	rw.mu.Lock()
+
+	if rw.closed || len(rw.pending) >= rw.conf.MaxPending {
+		rw.mu.Unlock()
+		rw.spill([][]byte{p})
+		return len(p), nil
+	}
+
+	// p is pooled in zerolog, hence the copy
+	rw.pending = append(rw.pending, append([]byte(nil), p...))
+	full := len(rw.pending) >= rw.conf.BatchSize
+	rw.mu.Unlock()
+
+	if full {
+		select {
+		case rw.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
 }
 
 // WriteLevel write data to writer with level info provided
-func (c RedisConfig) WriteLevel(level Level, p []byte) (n int, err error) {
-	if level < c.Level {
+func (rw *redisWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	if level < rw.conf.Level {
 		return len(p), nil
 	}
 
-	return c.Write(p)
+	return rw.Write(p)
+}
+
+// Close flushes pending lines and closes client.
+func (rw *redisWriter) Close() error {
+	rw.mu.Lock()
+	if rw.closed {
+		rw.mu.Unlock()
+		return nil
+	}
+	rw.closed = true
+	rw.mu.Unlock()
+
+	close(rw.stop)
+	<-rw.done
+
+	return rw.conf.client.Close()
+}
+
+func (rw *redisWriter) loop() {
+	defer close(rw.done)
+
+	ticker := time.NewTicker(rw.conf.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-rw.flushCh:
+		case <-rw.stop:
+			rw.flush(true)
+			return
+		}
+
+		rw.flush(false)
+	}
+}
+
+// flush pushes pending lines batch by batch, lines spill if redis is in backoff or
+// push fails. force ignores backoff, it's used on close, all pending lines spill after
+// the first failed push.
+func (rw *redisWriter) flush(force bool) {
+	for {
+		rw.mu.Lock()
+		n := len(rw.pending)
+		if n == 0 {
+			rw.mu.Unlock()
+			return
+		}
+		if n > rw.conf.BatchSize {
+			n = rw.conf.BatchSize
+		}
+		batch := rw.pending[:n:n]
+		rw.pending = rw.pending[n:]
+		rw.mu.Unlock()
+
+		if !force && time.Now().Before(rw.retryAt) {
+			// keep it until redis is back, spill if too many
+			rw.requeue(batch)
+			return
+		}
+
+		if err := rw.push(batch); err != nil {
+			rw.spill(batch)
+
+			if rw.backoff == 0 {
+				rw.backoff = redisMinBackoff
+			} else if rw.backoff *= 2; rw.backoff > redisMaxBackoff {
+				rw.backoff = redisMaxBackoff
+			}
+			rw.retryAt = time.Now().Add(rw.backoff)
+
+			log.Printf("Redis Writer push err: %s, retry in %s", err, rw.backoff)
+
+			if force {
+				// redis is down on close, don't pay dial timeout of each batch
+				rw.mu.Lock()
+				rest := rw.pending
+				rw.pending = nil
+				rw.mu.Unlock()
+
+				if len(rest) != 0 {
+					rw.spill(rest)
+				}
+			}
+			return
+		}
+
+		rw.backoff = 0
+		rw.retryAt = time.Time{}
+	}
+}
+
+// requeue puts batch back to head of pending, lines exceed MaxPending spill.
+func (rw *redisWriter) requeue(batch [][]byte) {
+	rw.mu.Lock()
+	pending := append(batch, rw.pending...)
+	var spilled [][]byte
+	if len(pending) > rw.conf.MaxPending {
+		spilled = pending[:len(pending)-rw.conf.MaxPending]
+		pending = pending[len(pending)-rw.conf.MaxPending:]
+	}
+	rw.pending = pending
+	rw.mu.Unlock()
+
+	if len(spilled) != 0 {
+		rw.spill(spilled)
+	}
+}
+
+func (rw *redisWriter) push(batch [][]byte) error {
+	c := rw.conf
+
+	_, err := c.client.Pipelined(func(pipe redis.Pipeliner) error {
+		if c.Stream {
+			for i := range batch {
+				pipe.XAdd(&redis.XAddArgs{
+					Stream:       c.LogKey,
+					MaxLenApprox: c.MaxLen,
+					Values:       map[string]interface{}{"log": batch[i]},
+				})
+			}
+			return nil
+		}
+
+		values := make([]interface{}, len(batch))
+		for i := range batch {
+			values[i] = batch[i]
+		}
+		pipe.RPush(c.LogKey, values...)
+
+		if c.MaxLen > 0 {
+			pipe.LTrim(c.LogKey, -c.MaxLen, -1)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (rw *redisWriter) spill(lines [][]byte) {
+	for i := range lines {
+		rw.conf.Spill.Write(lines[i])
+	}
 }
@@ -0,0 +1,252 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// BurstSampler lets Burst events pass per Period then pass the decision to
+// NextSampler, events are dropped if NextSampler is nil.
+type BurstSampler = zerolog.BurstSampler
+
+// LevelSampler applies a different sampler for each level.
+type LevelSampler = zerolog.LevelSampler
+
+// TokenBucketSampler lets events pass at Rate per second with bursts of at most Burst events.
+type TokenBucketSampler struct {
+	Rate  float64
+	Burst int // default 1
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Sample implements the Sampler interface.
+func (s *TokenBucketSampler) Sample(lvl Level) bool {
+	burst := float64(s.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last.IsZero() {
+		s.tokens = burst
+	} else {
+		s.tokens += now.Sub(s.last).Seconds() * s.Rate
+		if s.tokens > burst {
+			s.tokens = burst
+		}
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// DedupSampler collapses identical events, same level, message and caller, in Window
+// into one. The first event is written, following ones in Window are dropped and counted,
+// the count is written as field repeated with the message when Window ends.
+//
+// It works as top-level sampler or in LevelSampler, it can't be NextSampler of others,
+// as they have no message of event, Sample and SetSampler panic on it.
+type DedupSampler struct {
+	Window      time.Duration // default 1s
+	MaxKeys     int           // max keys tracked, default 1000
+	NextSampler Sampler       // applied to events passed dedup, optional
+
+	mu sync.Mutex
+	m  map[dedupKey]*dedupEntry
+}
+
+type dedupKey struct {
+	lv  Level
+	msg string
+	pc  uintptr
+}
+
+type dedupEntry struct {
+	start    time.Time
+	repeated int
+	timer    *time.Timer // flushes repeated when window ends
+	l        Log         // logger of last dropped event
+}
+
+// Sample implements the Sampler interface, dedup is done by logger, so it only
+// applies NextSampler.
+func (s *DedupSampler) Sample(lvl Level) bool {
+	if s.NextSampler != nil {
+		return s.NextSampler.Sample(lvl)
+	}
+	return true
+}
+
+// allow checks if event of l should be written, repeated is count of events dropped in
+// last window if it's not flushed yet.
+func (s *DedupSampler) allow(l Log, lv Level, msg string, pc uintptr) (repeated int, ok bool) {
+	window := s.Window
+	if window <= 0 {
+		window = time.Second
+	}
+
+	maxKeys := s.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	now := time.Now()
+	key := dedupKey{lv: lv, msg: msg, pc: pc}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m == nil {
+		s.m = map[dedupKey]*dedupEntry{}
+	}
+
+	e, found := s.m[key]
+	if !found {
+		if len(s.m) >= maxKeys {
+			s.evict(now, window)
+			if len(s.m) >= maxKeys {
+				// too many keys, let it pass
+				return 0, true
+			}
+		}
+		s.m[key] = &dedupEntry{start: now}
+		return 0, true
+	}
+
+	if now.Sub(e.start) < window {
+		e.repeated++
+		e.l = l
+		if e.timer == nil {
+			e.timer = time.AfterFunc(e.start.Add(window).Sub(now), func() {
+				s.flush(key, e)
+			})
+		}
+		return 0, false
+	}
+
+	// window ended before timer fired
+	repeated = e.repeated
+	e.repeated = 0
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	s.m[key] = &dedupEntry{start: now}
+	return repeated, true
+}
+
+// flush writes repeated count of e with its message and removes e.
+func (s *DedupSampler) flush(key dedupKey, e *dedupEntry) {
+	s.mu.Lock()
+	repeated, l := e.repeated, e.l
+	e.repeated, e.l = 0, Log{}
+	if s.m[key] == e {
+		delete(s.m, key)
+	}
+	s.mu.Unlock()
+
+	if repeated != 0 {
+		l.write(key.lv, nil, repeated, key.pc, "%s", key.msg)
+	}
+}
+
+// evict removes expired keys, repeated counts of them are flushed by timers.
+func (s *DedupSampler) evict(now time.Time, window time.Duration) {
+	for k, e := range s.m {
+		if now.Sub(e.start) >= window {
+			delete(s.m, k)
+		}
+	}
+}
+
+type samplerHolder struct {
+	s Sampler
+}
+
+var gSampler atomic.Value // samplerHolder
+
+// SetSampler set global sampler used by loggers without own sampler, nil disables it.
+func SetSampler(s Sampler) {
+	checkSampler(s, false)
+	gSampler.Store(samplerHolder{s: s})
+}
+
+func globalSampler() Sampler {
+	h, _ := gSampler.Load().(samplerHolder)
+	return h.s
+}
+
+var errNestedDedup = errors.New("log: DedupSampler can't be NextSampler, use it as top-level sampler or in LevelSampler")
+
+// checkSampler panics if DedupSampler is NextSampler in s, next is true if s is NextSampler.
+func checkSampler(s Sampler, next bool) {
+	switch ss := s.(type) {
+	case *DedupSampler:
+		if next {
+			panic(errNestedDedup)
+		}
+		checkSampler(ss.NextSampler, true)
+	case *BurstSampler:
+		checkSampler(ss.NextSampler, true)
+	case LevelSampler:
+		for _, sub := range levelSamplers(ss) {
+			checkSampler(sub, next)
+		}
+	case *LevelSampler:
+		for _, sub := range levelSamplers(*ss) {
+			checkSampler(sub, next)
+		}
+	}
+}
+
+func levelSamplers(s LevelSampler) []Sampler {
+	return []Sampler{s.TraceSampler, s.DebugSampler, s.InfoSampler, s.WarnSampler, s.ErrorSampler}
+}
+
+// dedupOf returns DedupSampler of events of lv in s and sampler applied after dedup,
+// LevelSampler is resolved by lv.
+func dedupOf(s Sampler, lv Level) (*DedupSampler, Sampler) {
+	switch ss := s.(type) {
+	case *DedupSampler:
+		return ss, ss.NextSampler
+	case LevelSampler:
+		if sub := samplerOfLevel(ss, lv); sub != nil {
+			if ds, next := dedupOf(sub, lv); ds != nil {
+				return ds, next
+			}
+		}
+	case *LevelSampler:
+		return dedupOf(*ss, lv)
+	}
+	return nil, s
+}
+
+func samplerOfLevel(s LevelSampler, lv Level) Sampler {
+	switch lv {
+	case zerolog.TraceLevel:
+		return s.TraceSampler
+	case DebugLevel:
+		return s.DebugSampler
+	case InfoLevel:
+		return s.InfoSampler
+	case WarnLevel:
+		return s.WarnSampler
+	case ErrorLevel:
+		return s.ErrorSampler
+	}
+	return nil
+}
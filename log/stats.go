@@ -0,0 +1,94 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+)
+
+// netDropped counts lines dropped by net and syslog writers while conn is down, by name.
+var netDropped = struct {
+	sync.Mutex
+	m map[string]uint64
+}{
+	m: map[string]uint64{},
+}
+
+func addNetDropped(name string) {
+	netDropped.Lock()
+	netDropped.m[name]++
+	netDropped.Unlock()
+}
+
+// NetWriterDropped returns count of lines dropped by net and syslog writers by name.
+func NetWriterDropped() map[string]uint64 {
+	netDropped.Lock()
+	defer netDropped.Unlock()
+
+	m := make(map[string]uint64, len(netDropped.m))
+	for k, v := range netDropped.m {
+		m[k] = v
+	}
+	return m
+}
+
+type namedAsync struct {
+	name  string
+	stats func() AsyncStats
+}
+
+var asyncWriters = struct {
+	sync.Mutex
+	list []namedAsync
+}{}
+
+// registerAsync records async writer for stats, name is made unique.
+func registerAsync(name string, s *asyncState, stats func() AsyncStats) {
+	if name == "" {
+		name = "async"
+	}
+
+	asyncWriters.Lock()
+	defer asyncWriters.Unlock()
+
+	unique := name
+	for i := 1; ; i++ {
+		dup := false
+		for _, v := range asyncWriters.list {
+			if v.name == unique {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			break
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+
+	s.name = unique
+	asyncWriters.list = append(asyncWriters.list, namedAsync{name: unique, stats: stats})
+}
+
+func unregisterAsync(s *asyncState) {
+	asyncWriters.Lock()
+	defer asyncWriters.Unlock()
+
+	for i, v := range asyncWriters.list {
+		if v.name == s.name {
+			asyncWriters.list = append(asyncWriters.list[:i], asyncWriters.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// AsyncWriterStats returns stats of all async writers by name.
+func AsyncWriterStats() map[string]AsyncStats {
+	asyncWriters.Lock()
+	defer asyncWriters.Unlock()
+
+	m := make(map[string]AsyncStats, len(asyncWriters.list))
+	for _, v := range asyncWriters.list {
+		m[v.name] = v.stats()
+	}
+	return m
+}
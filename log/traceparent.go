@@ -0,0 +1,140 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// TraceParentKey is header or metadata key of W3C trace context.
+const TraceParentKey = "traceparent"
+
+// TraceParent is W3C trace context, see https://www.w3.org/TR/trace-context/.
+type TraceParent struct {
+	TraceID string // 32 lowercase hex
+	SpanID  string // 16 lowercase hex, parent-id in header
+	Flags   byte   // 0x01 means sampled
+}
+
+// NewTraceParent returns sampled trace context with random trace id and span id.
+func NewTraceParent() TraceParent {
+	return TraceParent{
+		TraceID: randHex(16),
+		SpanID:  randHex(8),
+		Flags:   0x01,
+	}
+}
+
+// ParseTraceParent parses traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceParent(s string) (TraceParent, bool) {
+	// version-traceid-spanid-flags
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return TraceParent{}, false
+	}
+
+	version := s[:2]
+	if !isHex(version) || version == "ff" {
+		return TraceParent{}, false
+	}
+
+	// future versions may append fields
+	if len(s) > 55 && (version == "00" || s[55] != '-') {
+		return TraceParent{}, false
+	}
+
+	flags, err := hex.DecodeString(s[53:55])
+	if err != nil {
+		return TraceParent{}, false
+	}
+
+	tp := TraceParent{
+		TraceID: s[3:35],
+		SpanID:  s[36:52],
+		Flags:   flags[0],
+	}
+
+	if !tp.IsValid() {
+		return TraceParent{}, false
+	}
+
+	return tp, true
+}
+
+// IsValid checks ids are lowercase hex and not all zero.
+func (tp TraceParent) IsValid() bool {
+	return len(tp.TraceID) == 32 && isHex(tp.TraceID) && !isZero(tp.TraceID) &&
+		len(tp.SpanID) == 16 && isHex(tp.SpanID) && !isZero(tp.SpanID)
+}
+
+// Sampled checks sampled flag.
+func (tp TraceParent) Sampled() bool {
+	return tp.Flags&0x01 == 0x01
+}
+
+// Child returns trace context of same trace with new span id.
+func (tp TraceParent) Child() TraceParent {
+	tp.SpanID = randHex(8)
+	return tp
+}
+
+// String returns traceparent header value.
+func (tp TraceParent) String() string {
+	if !tp.IsValid() {
+		return ""
+	}
+	return "00-" + tp.TraceID + "-" + tp.SpanID + "-" + hex.EncodeToString([]byte{tp.Flags})
+}
+
+// WithTraceParent returns logger with trace_id and span_id fields.
+func WithTraceParent(tp TraceParent) Log {
+	return logger.WithTraceParent(tp)
+}
+
+// WithTraceParent returns copy of l with trace_id and span_id fields, invalid tp is ignored,
+// previous ones are replaced.
+func (l Log) WithTraceParent(tp TraceParent) Log {
+	if !tp.IsValid() {
+		return l
+	}
+	l.tp = tp
+	return l.replaceKV("trace_id", tp.TraceID).replaceKV("span_id", tp.SpanID)
+}
+
+// TraceParent returns trace context of l.
+func (l Log) TraceParent() TraceParent {
+	return l.tp
+}
+
+// TraceParentFromContext returns trace context of Log attached to ctx.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	tp := Ctx(ctx).tp
+	return tp, tp.IsValid()
+}
+
+func randHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '0' {
+			return false
+		}
+	}
+	return true
+}
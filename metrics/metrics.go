@@ -0,0 +1,123 @@
+// Package metrics holds the prometheus registry shared by go-lib packages,
+// collectors registered here are exposed by Handler in text exposition format.
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// alias
+type (
+	Collector    = prometheus.Collector
+	Desc         = prometheus.Desc
+	Metric       = prometheus.Metric
+	Labels       = prometheus.Labels
+	CounterVec   = prometheus.CounterVec
+	GaugeVec     = prometheus.GaugeVec
+	HistogramVec = prometheus.HistogramVec
+)
+
+// Registry is default registry with go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Register registers collector to Registry, AlreadyRegisteredError is ignored.
+func Register(c Collector) error {
+	err := Registry.Register(c)
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
+
+// MustRegister registers collectors to Registry, it panics if any err occurs.
+func MustRegister(cs ...Collector) {
+	Registry.MustRegister(cs...)
+}
+
+// Unregister unregisters collector from Registry.
+func Unregister(c Collector) bool {
+	return Registry.Unregister(c)
+}
+
+// Handler returns http handler of Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewCounterVec creates counter vec and registers it to Registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name,
+		Help: help,
+	}, labels)
+	MustRegister(c)
+	return c
+}
+
+// NewGaugeVec creates gauge vec and registers it to Registry.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: name,
+		Help: help,
+	}, labels)
+	MustRegister(g)
+	return g
+}
+
+// NewHistogramVec creates histogram vec and registers it to Registry.
+// prometheus.DefBuckets is used if buckets is nil.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name,
+		Help:    help,
+		Buckets: buckets,
+	}, labels)
+	MustRegister(h)
+	return h
+}
+
+// NewDesc is alias of prometheus.NewDesc, used by custom collector.
+func NewDesc(name, help string, labels []string, constLabels Labels) *Desc {
+	return prometheus.NewDesc(name, help, labels, constLabels)
+}
+
+// MustNewGauge is helper func for custom collector.
+func MustNewGauge(desc *Desc, value float64, labels ...string) Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
+
+// MustNewCounter is helper func for custom collector.
+func MustNewCounter(desc *Desc, value float64, labels ...string) Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labels...)
+}
+
+var buildInfo = struct {
+	sync.Mutex
+	g *prometheus.GaugeVec
+}{}
+
+// SetBuildInfo sets build_info gauge, value is always 1.
+func SetBuildInfo(version, gitCommit, buildDate string) {
+	buildInfo.Lock()
+	defer buildInfo.Unlock()
+
+	if buildInfo.g == nil {
+		buildInfo.g = NewGaugeVec("build_info", "build info of binary, value is always 1.",
+			"version", "git_commit", "build_date", "go_version")
+	}
+
+	buildInfo.g.Reset()
+	buildInfo.g.WithLabelValues(version, gitCommit, buildDate, runtime.Version()).Set(1)
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	SetBuildInfo("1.0", "d12e63e8", "2018-08-26")
+
+	c := NewCounterVec("test_requests_total", "test counter.", "code")
+	c.WithLabelValues("200").Inc()
+
+	rw := httptest.NewRecorder()
+	Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rw.Body.String()
+	for _, s := range []string{
+		`build_info{build_date="2018-08-26",git_commit="d12e63e8",go_version=`,
+		`test_requests_total{code="200"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("metrics should contain %q", s)
+		}
+	}
+}
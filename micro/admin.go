@@ -0,0 +1,157 @@
+package micro
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"strings"
+
+	"github.com/arcplus/go-lib/config"
+	"github.com/arcplus/go-lib/json"
+	"github.com/arcplus/go-lib/log"
+	"github.com/arcplus/go-lib/metrics"
+)
+
+// AdminTokenHeader is header of admin token, required by debug endpoints in prod mode.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminBind is a helper func to read admin port from flag or env admin_p and returns bind addr.
+func AdminBind(port string) string {
+	return Bind(port, "admin_p")
+}
+
+// ServeAdmin is helper func to start admin http server.
+//
+// health probes and /metrics are always served, debug endpoints under /debug/ are enabled
+// by default outside prod mode, in prod mode admin_token must be set and carried by AdminTokenHeader.
+//
+//	/debug/pprof/      net/http/pprof
+//	/debug/goroutines  goroutine dump
+//	/debug/version     VersionInfo
+//	/debug/config      effective config, secrets redacted
+//	/debug/loglevel    GET current log level, PUT to change it, ?name= for named loggers
+func (m *micro) ServeAdmin(bindAddr string) {
+	m.ServeHTTP(bindAddr, m.adminHandler())
+}
+
+func (m *micro) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	health := m.HealthHandler()
+	mux.Handle("/healthz", health)
+	mux.Handle("/livez", health)
+	mux.Handle("/readyz", health)
+	mux.Handle("/metrics", metrics.Handler())
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", serveGoroutines)
+	mux.HandleFunc("/debug/version", serveVersion)
+	mux.HandleFunc("/debug/config", serveConfig)
+	mux.HandleFunc("/debug/loglevel", serveLogLevel)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/debug/") && !m.debugAllowed(r) {
+			http.Error(rw, "forbidden", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+// debugAllowed checks admin token in prod mode.
+func (m *micro) debugAllowed(r *http.Request) bool {
+	if !ProdMode() {
+		return true
+	}
+
+	token := m.Conf().AdminToken
+	if token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) == 1
+}
+
+func serveGoroutines(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Header().Set("X-Goroutine-Count", strconv.Itoa(runtime.NumGoroutine()))
+	rpprof.Lookup("goroutine").WriteTo(rw, 2)
+}
+
+func serveVersion(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.Write([]byte(VersionInfo()))
+}
+
+func serveConfig(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.Write(json.MustMarshal(config.Effective()))
+}
+
+// serveLogLevel GET returns global log level, PUT changes it, level is read from
+// query level or body. With query name, level of named loggers matching it is used,
+// name can be glob, e.g. "mysql", "grpc*", PUT level "unset" resets them to global level.
+func serveLogLevel(rw http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		lv := r.URL.Query().Get("level")
+		if lv == "" {
+			data, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, 64))
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			lv = strings.TrimSpace(string(data))
+		}
+
+		switch lv {
+		case "debug", "info", "warn", "error":
+		case "unset":
+			if name != "" {
+				break
+			}
+			fallthrough
+		default:
+			http.Error(rw, "level should be one of debug, info, warn, error", http.StatusBadRequest)
+			return
+		}
+
+		if name == "" {
+			log.SetGlobalLevel(getLogLevel(lv))
+			log.Warnf("micro log level changed to %s by %s", lv, r.RemoteAddr)
+			break
+		}
+
+		if lv == "unset" {
+			log.UnsetLevel(name)
+		} else if err := log.SetLevel(name, getLogLevel(lv)); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Warnf("micro log level of %s changed to %s by %s", name, lv, r.RemoteAddr)
+	default:
+		rw.Header().Set("Allow", "GET, PUT")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if name != "" {
+		rw.Write([]byte(log.NamedLevel(name).String()))
+		return
+	}
+
+	rw.Write([]byte(log.GlobalLevel().String()))
+}
@@ -0,0 +1,91 @@
+package micro
+
+import (
+	"os"
+	"time"
+
+	"github.com/arcplus/go-lib/config"
+	"github.com/arcplus/go-lib/log"
+)
+
+// ConfFile is optional conf file of micro, json, yaml or toml.
+var ConfFile = os.Getenv("conf_file")
+
+// Conf is micro conf, loaded from ConfFile, env and flags.
+type Conf struct {
+	Mode           string   `config:"mode"`
+	LogLevel       string   `config:"log_level" default:"debug"`
+	LogSync        bool     `config:"log_sync"`
+	LogAsyncPolicy string   `config:"log_async_policy" default:"drop_oldest"` // drop_oldest, drop_newest, block, never_drop_errors
+	LogFormat      string   `config:"log_format" default:"console"`           // console, json, logfmt
+	LogRedact      bool     `config:"log_redact" default:"false"`
+	LogRedactKeys  []string `config:"log_redact_keys"` // extra keys to log.DefaultRedactConfig
+	LogStdDisable  bool     `config:"log_std_disable"`
+	LogRdsDSN      string   `config:"log_rds_dsn" secret:"true"`
+	LogRdsKey      string   `config:"log_rds_key"`
+	LogRdsLevel    string   `config:"log_rds_level" default:"debug"`
+	LogRdsMaxLen   int64    `config:"log_rds_max_len"`
+	LogRdsStream   bool     `config:"log_rds_stream"`
+
+	LogFile           string        `config:"log_file"`
+	LogFileLevel      string        `config:"log_file_level" default:"debug"`
+	LogFileMaxSize    int           `config:"log_file_max_size"` // megabytes
+	LogFileInterval   time.Duration `config:"log_file_interval"`
+	LogFileMaxBackups int           `config:"log_file_max_backups"`
+	LogFileCompress   bool          `config:"log_file_compress"`
+
+	LogNetAddr    string        `config:"log_net_addr"`
+	LogNetNetwork string        `config:"log_net_network" default:"tcp"`
+	LogNetFraming string        `config:"log_net_framing" default:"newline"` // newline, octet
+	LogNetLevel   string        `config:"log_net_level" default:"debug"`
+	LogNetTimeout time.Duration `config:"log_net_timeout" default:"3s"` // dial and write timeout
+
+	LogSyslogAddr    string        `config:"log_syslog_addr"`
+	LogSyslogNetwork string        `config:"log_syslog_network" default:"udp"`
+	LogSyslogFraming string        `config:"log_syslog_framing" default:"octet"` // newline, octet, ignored by udp
+	LogSyslogLevel   string        `config:"log_syslog_level" default:"debug"`
+	LogSyslogTimeout time.Duration `config:"log_syslog_timeout" default:"3s"` // dial and write timeout
+
+	AdminToken string `config:"admin_token" secret:"true"` // required by debug endpoints in prod mode
+}
+
+// loadConf loads conf, on err conf with bad values skipped is returned along with err.
+func loadConf() (Conf, error) {
+	conf := Conf{}
+
+	var files []string
+	if ConfFile != "" {
+		files = append(files, ConfFile)
+	}
+
+	err := config.Load(&conf, files...)
+	if err == nil {
+		return conf, nil
+	}
+
+	// e.g. log_std_disable=yes
+	conf = Conf{}
+	l := &config.Loader{Files: files, Lenient: true}
+	l.Load(&conf)
+
+	return conf, err
+}
+
+// setLogRedact set log redaction by conf.
+func setLogRedact(conf Conf) {
+	if !conf.LogRedact {
+		log.DisableRedact()
+		return
+	}
+
+	rc := log.DefaultRedactConfig
+	rc.Keys = append(rc.Keys[:len(rc.Keys):len(rc.Keys)], conf.LogRedactKeys...)
+	log.SetRedact(rc)
+}
+
+// Conf returns effective conf of micro.
+func (m *micro) Conf() Conf {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conf
+}
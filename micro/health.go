@@ -0,0 +1,173 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/arcplus/go-lib/json"
+	"github.com/arcplus/go-lib/now"
+)
+
+// HealthChecker checks if a dependency is available, e.g. mysql.HealthCheck, redis.HealthCheck.
+type HealthChecker func() error
+
+// ContextHealthChecker is HealthChecker with ctx, ctx is done after HealthCheckTimeout.
+type ContextHealthChecker func(ctx context.Context) error
+
+// HealthCheckTimeout is timeout of each readiness check, the check fails if it's exceeded.
+var HealthCheckTimeout = 3 * time.Second
+
+// DrainDelay is the time to wait after readiness flipped to "not ready" and before resources
+// are closed, so load balancers have a chance to remove the instance.
+var DrainDelay time.Duration
+
+// service state
+const (
+	stateInit int32 = iota
+	stateReady
+	stateStopping
+)
+
+type namedChecker struct {
+	name    string
+	checker ContextHealthChecker
+}
+
+type checkResult struct {
+	Status string  `json:"status"`
+	Error  string  `json:"error,omitempty"`
+	Took   float64 `json:"took"` // ms
+}
+
+type healthResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// AddHealthCheck register readiness checker with name.
+func (m *micro) AddHealthCheck(name string, checker HealthChecker) {
+	if checker == nil {
+		return
+	}
+	m.AddHealthCheckContext(name, func(context.Context) error {
+		return checker()
+	})
+}
+
+// AddHealthCheckContext register readiness checker with name, checker should return
+// once ctx is done.
+func (m *micro) AddHealthCheckContext(name string, checker ContextHealthChecker) {
+	if checker == nil {
+		return
+	}
+	m.mu.Lock()
+	m.checkers = append(m.checkers, namedChecker{name: name, checker: checker})
+	m.mu.Unlock()
+}
+
+// Ready reports whether micro is started and not stopping.
+func (m *micro) Ready() bool {
+	return atomic.LoadInt32(&m.state) == stateReady
+}
+
+func (m *micro) setState(s int32) {
+	atomic.StoreInt32(&m.state, s)
+}
+
+// HealthHandler returns handler serving liveness and readiness probes.
+// path ends with /healthz or /livez is liveness, /readyz is readiness.
+// It can be mounted onto user's own http handler, or served with ServeAdmin.
+func (m *micro) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/readyz"):
+			m.serveReadiness(rw, r)
+		case strings.HasSuffix(r.URL.Path, "/healthz"), strings.HasSuffix(r.URL.Path, "/livez"):
+			writeHealth(rw, http.StatusOK, healthResult{Status: "ok"})
+		default:
+			http.NotFound(rw, r)
+		}
+	})
+}
+
+func (m *micro) serveReadiness(rw http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	checkers := make([]namedChecker, len(m.checkers))
+	copy(checkers, m.checkers)
+	m.mu.Unlock()
+
+	result := healthResult{
+		Status: "ok",
+		Checks: make(map[string]checkResult, len(checkers)),
+	}
+
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	for i := range checkers {
+		wg.Add(1)
+		go func(nc namedChecker) {
+			defer wg.Done()
+			start := time.Now()
+			cr := checkResult{Status: "ok"}
+			if err := runCheck(r.Context(), nc.checker); err != nil {
+				cr.Status = "fail"
+				cr.Error = err.Error()
+			}
+			cr.Took = now.NanoToMs(time.Since(start).Nanoseconds())
+			mu.Lock()
+			result.Checks[nc.name] = cr
+			mu.Unlock()
+		}(checkers[i])
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	for _, cr := range result.Checks {
+		if cr.Status != "ok" {
+			result.Status = "fail"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	switch atomic.LoadInt32(&m.state) {
+	case stateInit:
+		result.Status = "starting"
+		code = http.StatusServiceUnavailable
+	case stateStopping:
+		result.Status = "stopping"
+		code = http.StatusServiceUnavailable
+	}
+
+	writeHealth(rw, code, result)
+}
+
+// runCheck runs checker with HealthCheckTimeout, it returns once timeout even if checker
+// ignores ctx.
+func runCheck(ctx context.Context, checker ContextHealthChecker) error {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- checker(ctx)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return errors.New("timeout after " + HealthCheckTimeout.String())
+	}
+}
+
+func writeHealth(rw http.ResponseWriter, code int, result healthResult) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(code)
+	rw.Write(json.MustMarshal(result))
+}
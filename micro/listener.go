@@ -0,0 +1,297 @@
+package micro
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// env used to pass listeners to child process, fds start from 3,
+// value is bind addrs in fd order, separated by comma.
+const envInheritFds = "micro_inherit_fds"
+
+// env used to pass write end of readiness pipe to child process, value is fd,
+// child writes a byte to it once it's ready.
+const envReadyFd = "micro_ready_fd"
+
+// systemd socket activation env
+const (
+	envListenFds     = "LISTEN_FDS"
+	envListenPid     = "LISTEN_PID"
+	envListenFdNames = "LISTEN_FDNAMES"
+)
+
+// first inherited fd, 0,1,2 are stdin, stdout, stderr
+const listenFdsStart = 3
+
+var (
+	// UpgradeEnable enables zero-downtime restart on UpgradeSignal, the new process
+	// inherits listeners and the old one stops gracefully.
+	UpgradeEnable = os.Getenv("micro_upgrade") == "true"
+
+	// UpgradeSignal notify signal to restart with a new binary.
+	UpgradeSignal = []os.Signal{syscall.SIGUSR2}
+
+	// UpgradeTimeout is max wait for the new process to be ready, it's killed and the old
+	// one keeps running if exceeded.
+	UpgradeTimeout = 30 * time.Second
+)
+
+type inheritedListener struct {
+	name string
+	ln   net.Listener
+}
+
+var inherited = struct {
+	sync.Mutex
+	once sync.Once
+	lns  []*inheritedListener
+}{}
+
+func loadInherited() {
+	inherited.once.Do(func() {
+		lns, err := parseInherited()
+		if err != nil {
+			log.Errorf("micro inherit listeners err: %s", err)
+		}
+		inherited.lns = lns
+	})
+}
+
+// takeInherited returns listener inherited from parent or systemd matching bindAddr.
+func takeInherited(bindAddr string) net.Listener {
+	loadInherited()
+
+	inherited.Lock()
+	defer inherited.Unlock()
+
+	for i, v := range inherited.lns {
+		if v.name == bindAddr || sameAddr(v.ln.Addr(), bindAddr) {
+			inherited.lns = append(inherited.lns[:i], inherited.lns[i+1:]...)
+			return v.ln
+		}
+	}
+
+	return nil
+}
+
+// closeInherited closes inherited listeners not taken, it's called once startup is done.
+func closeInherited() {
+	loadInherited()
+
+	inherited.Lock()
+	defer inherited.Unlock()
+
+	for _, v := range inherited.lns {
+		log.Warnf("micro close unused inherited listener %s %s", v.name, v.ln.Addr())
+		v.ln.Close()
+	}
+	inherited.lns = nil
+}
+
+// notifyReady tells parent process of Upgrade this process is ready, env is unset after.
+func notifyReady() {
+	v := os.Getenv(envReadyFd)
+	if v == "" {
+		return
+	}
+	os.Unsetenv(envReadyFd)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		log.Errorf("micro bad %s %q", envReadyFd, v)
+		return
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	if _, err := f.Write([]byte{1}); err != nil {
+		log.Errorf("micro notify ready err: %s", err)
+	}
+	f.Close()
+}
+
+// parseInherited reads listeners from env, env is unset after parsed.
+func parseInherited() ([]*inheritedListener, error) {
+	var names []string
+	var n int
+
+	if v := os.Getenv(envInheritFds); v != "" {
+		os.Unsetenv(envInheritFds)
+		names = strings.Split(v, ",")
+		n = len(names)
+	} else if v := os.Getenv(envListenFds); v != "" {
+		pid, _ := strconv.Atoi(os.Getenv(envListenPid))
+		fdNames := os.Getenv(envListenFdNames)
+
+		os.Unsetenv(envListenFds)
+		os.Unsetenv(envListenPid)
+		os.Unsetenv(envListenFdNames)
+
+		if pid != os.Getpid() {
+			return nil, nil
+		}
+
+		var err error
+		n, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if fdNames != "" {
+			names = strings.Split(fdNames, ":")
+		}
+	}
+
+	lns := make([]*inheritedListener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		f := os.NewFile(uintptr(fd), "listener")
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return lns, err
+		}
+
+		il := &inheritedListener{ln: ln}
+		if i < len(names) {
+			il.name = names[i]
+		}
+		lns = append(lns, il)
+	}
+
+	return lns, nil
+}
+
+// sameAddr checks if addr is bindAddr, port 0 never matches.
+func sameAddr(addr net.Addr, bindAddr string) bool {
+	want, err := net.ResolveTCPAddr("tcp", bindAddr)
+	if err != nil || want.Port == 0 {
+		return false
+	}
+
+	got, ok := addr.(*net.TCPAddr)
+	if !ok || got.Port != want.Port {
+		return false
+	}
+
+	if len(want.IP) == 0 || want.IP.IsUnspecified() {
+		return len(got.IP) == 0 || got.IP.IsUnspecified()
+	}
+
+	return want.IP.Equal(got.IP)
+}
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrade starts a new process of the same binary, listeners are passed to it, it
+// returns once the new process is ready. The caller should stop gracefully if it succeeds.
+func (m *micro) Upgrade() error {
+	name, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	p, ready, err := m.spawn(name, os.Args[1:], os.Environ())
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	if err := waitReady(ready, UpgradeTimeout); err != nil {
+		p.Kill()
+		go p.Wait()
+		return err
+	}
+
+	go p.Wait() // child is reparented once we exit
+	return nil
+}
+
+// waitReady waits for the byte written by notifyReady of child.
+func waitReady(ready *os.File, timeout time.Duration) error {
+	ready.SetReadDeadline(time.Now().Add(timeout))
+
+	_, err := ready.Read(make([]byte, 1))
+	if err == io.EOF {
+		return errors.New("child exited before ready")
+	}
+	return err
+}
+
+// spawn starts child process with listeners, read end of readiness pipe is returned.
+func (m *micro) spawn(name string, args []string, env []string) (*os.Process, *os.File, error) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.listeners))
+	files := make([]*os.File, 0, len(m.listeners))
+	for addr, ln := range m.listeners {
+		fl, ok := ln.(filer)
+		if !ok {
+			continue
+		}
+		f, err := fl.File()
+		if err != nil {
+			m.mu.Unlock()
+			closeFiles(files)
+			return nil, nil, err
+		}
+		names = append(names, addr)
+		files = append(files, f)
+	}
+	m.mu.Unlock()
+
+	defer closeFiles(files)
+
+	if len(files) == 0 {
+		return nil, nil, errors.New("no listener to pass")
+	}
+
+	ready, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	// write end is closed here after child started, so read gets EOF if child exits
+	defer w.Close()
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+
+	for _, e := range env {
+		if strings.HasPrefix(e, envInheritFds+"=") || strings.HasPrefix(e, envReadyFd+"=") ||
+			strings.HasPrefix(e, "LISTEN_") {
+			continue
+		}
+		cmd.Env = append(cmd.Env, e)
+	}
+	cmd.Env = append(cmd.Env,
+		envInheritFds+"="+strings.Join(names, ","),
+		envReadyFd+"="+strconv.Itoa(listenFdsStart+len(names)))
+
+	if err := cmd.Start(); err != nil {
+		ready.Close()
+		return nil, nil, err
+	}
+
+	return cmd.Process, ready, nil
+}
+
+func closeFiles(files []*os.File) {
+	for i := range files {
+		files[i].Close()
+	}
+}
@@ -0,0 +1,48 @@
+package micro
+
+import (
+	"github.com/arcplus/go-lib/log"
+	"github.com/arcplus/go-lib/metrics"
+)
+
+func init() {
+	metrics.MustRegister(newLogCollector())
+}
+
+// logCollector collects stats of async and network log writers.
+type logCollector struct {
+	written    *metrics.Desc
+	dropped    *metrics.Desc
+	queued     *metrics.Desc
+	netDropped *metrics.Desc
+}
+
+func newLogCollector() *logCollector {
+	labels := []string{"writer"}
+	return &logCollector{
+		written:    metrics.NewDesc("log_async_written_total", "Total number of log events written by async writer.", labels, nil),
+		dropped:    metrics.NewDesc("log_async_dropped_total", "Total number of log events dropped by async writer.", labels, nil),
+		queued:     metrics.NewDesc("log_async_queue_depth", "Number of log events in queue of async writer.", labels, nil),
+		netDropped: metrics.NewDesc("log_net_dropped_total", "Total number of log lines dropped by network writer.", labels, nil),
+	}
+}
+
+// Describe implements metrics.Collector
+func (c *logCollector) Describe(ch chan<- *metrics.Desc) {
+	ch <- c.written
+	ch <- c.dropped
+	ch <- c.queued
+	ch <- c.netDropped
+}
+
+// Collect implements metrics.Collector
+func (c *logCollector) Collect(ch chan<- metrics.Metric) {
+	for name, s := range log.AsyncWriterStats() {
+		ch <- metrics.MustNewCounter(c.written, float64(s.Written), name)
+		ch <- metrics.MustNewCounter(c.dropped, float64(s.Dropped), name)
+		ch <- metrics.MustNewGauge(c.queued, float64(s.Queued), name)
+	}
+	for name, n := range log.NetWriterDropped() {
+		ch <- metrics.MustNewCounter(c.netDropped, float64(n), name)
+	}
+}
@@ -2,7 +2,6 @@ package micro
 
 import (
 	"bytes"
-	"container/list"
 	"context"
 	"fmt"
 	"io"
@@ -16,7 +15,10 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/arcplus/go-lib/config"
+	"github.com/arcplus/go-lib/errs"
 	"github.com/arcplus/go-lib/log"
+	"github.com/arcplus/go-lib/metrics"
 )
 
 // go build -ldflags -X
@@ -35,17 +37,48 @@ func VersionInfo() string {
 
 type Micro interface {
 	AddResCloseFunc(f func() error)
+	AddHealthCheck(name string, checker HealthChecker)
+	AddHealthCheckContext(name string, checker ContextHealthChecker)
+	AddShutdownHook(phase Phase, name string, f func(ctx context.Context) error, timeout ...time.Duration)
+	AddWorker(name string, w Worker, conf ...WorkerConf)
 	Close()
+	Conf() Conf
+	HealthHandler() http.Handler
+	OnStart(f func() error)
+	OnReload(f func() error)
+	OnStop(f func() error)
+	Ready() bool
+	Reload() error
+	ServeAdmin(bindAddr string)
 	ServeGRPC(bindAddr string, server GRPCServer)
 	ServeHTTP(bindAddr string, handler http.Handler)
+	SetShutdownTimeout(d time.Duration)
 	Start()
+	Upgrade() error
 }
 
 type micro struct {
-	mu            *sync.Mutex
-	errChan       chan error
-	serveFuncs    []func()
-	resCloseFuncs *list.List
+	mu              *sync.Mutex
+	state           int32
+	errChan         chan error
+	serveFuncs      []func()
+	workers         []func()
+	startHooks      []func() error
+	reloadHooks     []func() error
+	hooks           [phaseCount][]shutdownHook
+	shutdownTimeout time.Duration
+	checkers        []namedChecker
+	rdsWriter       *levelWriter
+	fileWriter      *levelWriter
+	netWriter       *levelWriter
+	syslogWriter    *levelWriter
+	listeners       map[string]net.Listener
+	conf            Conf
+
+	// workers ctx
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
 }
 
 var mode = os.Getenv("mode")
@@ -64,12 +97,29 @@ func Mode() string {
 // New create Micro, serviceName.0 is service name.
 func New(serviceName ...string) Micro {
 	m := &micro{
-		mu:            &sync.Mutex{},
-		errChan:       make(chan error, 1),
-		serveFuncs:    make([]func(), 0),
-		resCloseFuncs: list.New(),
+		mu:              &sync.Mutex{},
+		errChan:         make(chan error, 1),
+		serveFuncs:      make([]func(), 0),
+		shutdownTimeout: ShutdownTimeout,
+		wg:              &sync.WaitGroup{},
+		listeners:       make(map[string]net.Listener),
 	}
 
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	conf, err := loadConf()
+	if err != nil {
+		log.Errorf("micro load conf err: %s, bad values are ignored", err)
+	}
+	m.conf = conf
+
+	if conf.Mode != "" {
+		mode = conf.Mode
+	}
+
+	// no debug stack in gRPC error details for prod
+	errs.ProdMode = ProdMode()
+
 	kv := map[string]interface{}{}
 
 	if len(serviceName) != 0 {
@@ -84,68 +134,115 @@ func New(serviceName ...string) Micro {
 		kv["version"] = version + "_" + gitCommit
 	}
 
+	log.SetFormat(log.Format(conf.LogFormat))
+	setLogRedact(conf)
+
 	log.SetAttachment(kv)
 
-	level := getLogLevel(os.Getenv("log_level"))
+	metrics.SetBuildInfo(version, gitCommit, buildDate)
+
+	level := getLogLevel(conf.LogLevel)
 	log.SetGlobalLevel(level)
 
 	ws := []io.Writer{}
 
-	async := os.Getenv("log_sync") == "true"
+	async := conf.LogSync
+	policy := getAsyncPolicy(conf.LogAsyncPolicy)
 
-	if os.Getenv("log_std_disable") != "true" {
+	if !conf.LogStdDisable {
 		ws = append(ws, log.ConsoleWriter(
 			log.ConsoleConfig{
-				Async: async,
+				Async:  async,
+				Policy: policy,
 			},
 		))
 	}
 
-	if rds, key := os.Getenv("log_rds_dsn"), os.Getenv("log_rds_key"); rds != "" && key != "" {
-		ws = append(ws, log.RedisWriter(log.RedisConfig{
-			Level:  getLogLevel(os.Getenv("log_rds_level")),
+	if rds, key := conf.LogRdsDSN, conf.LogRdsKey; rds != "" && key != "" {
+		// level is filtered by rdsWriter, so it can be changed on reload
+		m.rdsWriter = newLevelWriter(getLogLevel(conf.LogRdsLevel), log.RedisWriter(log.RedisConfig{
+			Level:  log.DebugLevel,
 			DSN:    rds,
 			LogKey: key,
 			Async:  async,
+			Policy: policy,
+			MaxLen: conf.LogRdsMaxLen,
+			Stream: conf.LogRdsStream,
+		}))
+		ws = append(ws, m.rdsWriter)
+	}
+
+	if conf.LogFile != "" {
+		m.fileWriter = newLevelWriter(getLogLevel(conf.LogFileLevel), log.FileWriter(log.FileConfig{
+			Level:      log.DebugLevel,
+			Filename:   conf.LogFile,
+			MaxSize:    conf.LogFileMaxSize,
+			Interval:   conf.LogFileInterval,
+			MaxBackups: conf.LogFileMaxBackups,
+			Compress:   conf.LogFileCompress,
+			Async:      async,
+			Policy:     policy,
+		}))
+		ws = append(ws, m.fileWriter)
+	}
+
+	if conf.LogNetAddr != "" {
+		m.netWriter = newLevelWriter(getLogLevel(conf.LogNetLevel), log.NetWriter(log.NetConfig{
+			Level:   log.DebugLevel,
+			Network: conf.LogNetNetwork,
+			Addr:    conf.LogNetAddr,
+			Framing: getLogFraming(conf.LogNetFraming),
+			Timeout: conf.LogNetTimeout,
+			Async:   async,
+			Policy:  policy,
+		}))
+		ws = append(ws, m.netWriter)
+	}
+
+	if conf.LogSyslogAddr != "" {
+		m.syslogWriter = newLevelWriter(getLogLevel(conf.LogSyslogLevel), log.SyslogWriter(log.SyslogConfig{
+			Level:   log.DebugLevel,
+			Network: conf.LogSyslogNetwork,
+			Addr:    conf.LogSyslogAddr,
+			Framing: getLogFraming(conf.LogSyslogFraming),
+			Timeout: conf.LogSyslogTimeout,
+			Async:   async,
+			Policy:  policy,
 		}))
+		ws = append(ws, m.syslogWriter)
 	}
 
 	if len(ws) != 0 {
 		log.SetOutput(ws...)
 	}
 
-	m.AddResCloseFunc(log.Close)
+	m.AddShutdownHook(PhaseDrainServers, "workers", m.stopWorkers)
+
+	m.AddShutdownHook(PhaseFlushLogs, "log", func(context.Context) error {
+		return log.Close()
+	})
 
 	return m
 }
 
-// Close close all added resource FILO
-func (m *micro) Close() {
-	m.mu.Lock()
-	for m.resCloseFuncs.Len() != 0 {
-		e := m.resCloseFuncs.Back()
-		if f, ok := e.Value.(func() error); ok && f != nil {
-			err := f()
-			if err != nil {
-				log.Errorf("close resource err: %s", err.Error())
-			}
+// createListener reuses listener inherited from parent or systemd if possible.
+func (m *micro) createListener(bindAddr string) (net.Listener, error) {
+	ln := takeInherited(bindAddr)
+	if ln != nil {
+		log.Infof("micro inherit listener %s", ln.Addr())
+	} else {
+		var err error
+		ln, err = net.Listen("tcp", bindAddr)
+		if err != nil {
+			return nil, err
 		}
-		m.resCloseFuncs.Remove(e)
 	}
-	m.mu.Unlock()
-}
 
-// TODO ln reuse?
-func (m *micro) createListener(bindAddr string) (net.Listener, error) {
 	m.mu.Lock()
-	ln, err := net.Listen("tcp", bindAddr)
-	if err != nil {
-		m.mu.Unlock()
-		return nil, err
-	}
+	m.listeners[bindAddr] = ln
 	m.mu.Unlock()
 
-	m.AddResCloseFunc(func() error {
+	m.AddShutdownHook(PhaseDrainServers, "listener "+bindAddr, func(context.Context) error {
 		err := ln.Close()
 		if err != nil {
 			if _, ok := err.(*net.OpError); ok {
@@ -159,11 +256,15 @@ func (m *micro) createListener(bindAddr string) (net.Listener, error) {
 	return ln, nil
 }
 
-// AddResCloseFunc add resource close func
+// AddResCloseFunc add resource close func, it runs in PhaseCloseClients.
 func (m *micro) AddResCloseFunc(f func() error) {
-	m.mu.Lock()
-	m.resCloseFuncs.PushBack(f)
-	m.mu.Unlock()
+	if f == nil {
+		return
+	}
+
+	m.AddShutdownHook(PhaseCloseClients, funcName(f), func(context.Context) error {
+		return f()
+	})
 }
 
 // GRPCServer
@@ -177,18 +278,32 @@ func (m *micro) ServeGRPC(bindAddr string, server GRPCServer) {
 	m.serveFuncs = append(m.serveFuncs, func() {
 		ln, err := m.createListener(bindAddr)
 		if err != nil {
-			m.errChan <- err
+			m.fail(err)
 			return
 		}
 
-		m.AddResCloseFunc(func() error {
-			server.GracefulStop()
-			return nil
+		m.AddShutdownHook(PhaseDrainServers, "grpc "+bindAddr, func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				server.GracefulStop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				// force stop if possible
+				if s, ok := server.(interface{ Stop() }); ok {
+					s.Stop()
+				}
+				return ctx.Err()
+			}
 		})
 
 		err = server.Serve(ln)
 		if err != nil {
-			m.errChan <- err
+			m.fail(err)
 		}
 	})
 }
@@ -198,7 +313,7 @@ func (m *micro) ServeHTTP(bindAddr string, handler http.Handler) {
 	m.serveFuncs = append(m.serveFuncs, func() {
 		ln, err := m.createListener(bindAddr)
 		if err != nil {
-			m.errChan <- err
+			m.fail(err)
 			return
 		}
 
@@ -207,50 +322,108 @@ func (m *micro) ServeHTTP(bindAddr string, handler http.Handler) {
 			ReadHeaderTimeout: 30 * time.Second,
 		}
 
-		m.AddResCloseFunc(func() error {
-			ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*30)
-			defer cancelFunc()
-			return server.Shutdown(ctx)
+		m.AddShutdownHook(PhaseDrainServers, "http "+bindAddr, func(ctx context.Context) error {
+			err := server.Shutdown(ctx)
+			if err == ctx.Err() && err != nil {
+				// force close
+				server.Close()
+			}
+			return err
 		})
 
 		err = server.Serve(ln)
 		if err != nil {
-			m.errChan <- err
+			m.fail(err)
 		}
 	})
 }
 
 // WatchSignal notify signal to stop running
-var WatchSignal = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL, syscall.SIGHUP, syscall.SIGQUIT}
+var WatchSignal = []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL, syscall.SIGQUIT}
 
 // Wait util signal
 func (m *micro) Start() {
 	defer m.Close()
 
+	for i := range m.startHooks {
+		if err := m.startHooks[i](); err != nil {
+			log.Skip(1).Errorf("micro start hook err: %s", err)
+			return
+		}
+	}
+
 	for i := range m.serveFuncs {
 		go m.serveFuncs[i]()
 	}
 
+	m.wg.Add(len(m.workers))
+	for i := range m.workers {
+		go m.workers[i]()
+	}
+
+	closeInherited()
+
+	m.setState(stateReady)
+	notifyReady()
+
 	log.Info("micro start")
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, WatchSignal...)
-	select {
-	case s := <-ch:
-		log.Skip(1).Infof("micro receive stop signal: %s", s)
-	case e := <-m.errChan:
-		log.Skip(1).Errorf("micro receive err signal: %s", e)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, ReloadSignal...)
+
+	upgrade := make(chan os.Signal, 1)
+	if UpgradeEnable {
+		signal.Notify(upgrade, UpgradeSignal...)
+	}
+
+	defer signal.Stop(ch)
+	defer signal.Stop(reload)
+	defer signal.Stop(upgrade)
+
+	for {
+		select {
+		case s := <-upgrade:
+			log.Skip(1).Infof("micro receive upgrade signal: %s", s)
+			if err := m.Upgrade(); err != nil {
+				log.Skip(1).Errorf("micro upgrade err: %s", err)
+				continue
+			}
+			m.setState(stateStopping)
+			if DrainDelay > 0 {
+				time.Sleep(DrainDelay)
+			}
+			return
+		case s := <-reload:
+			log.Skip(1).Infof("micro receive reload signal: %s", s)
+			if err := m.Reload(); err != nil {
+				log.Skip(1).Errorf("micro reload err: %s", err)
+			}
+		case s := <-ch:
+			m.setState(stateStopping)
+			log.Skip(1).Infof("micro receive stop signal: %s", s)
+			if DrainDelay > 0 {
+				time.Sleep(DrainDelay)
+			}
+			return
+		case e := <-m.errChan:
+			m.setState(stateStopping)
+			log.Skip(1).Errorf("micro receive err signal: %s", e)
+			return
+		}
 	}
 }
 
-// bind is a helper func to read env port and returns bind addr
+// Bind is a helper func to read port from flag or env and returns bind addr
 func Bind(port string, envName ...string) string {
 	env := "p"
 	if len(envName) != 0 {
 		env = envName[0]
 	}
 
-	if p := os.Getenv(env); p != "" {
+	if p, _ := config.Lookup(env); p != "" {
 		port = p
 	}
 
@@ -267,6 +440,26 @@ func Bind(port string, envName ...string) string {
 	return port
 }
 
+func getAsyncPolicy(str string) log.Policy {
+	switch str {
+	case "drop_newest":
+		return log.DropNewest
+	case "block":
+		return log.Block
+	case "never_drop_errors":
+		return log.NeverDropErrors
+	default:
+		return log.DropOldest
+	}
+}
+
+func getLogFraming(str string) log.Framing {
+	if str == "octet" {
+		return log.FramingOctetCounting
+	}
+	return log.FramingNewline
+}
+
 func getLogLevel(str string) log.Level {
 	switch str {
 	case "info":
@@ -1,8 +1,21 @@
 package micro
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/arcplus/go-lib/errs"
+	"github.com/arcplus/go-lib/log"
 )
 
 func TestVersionInfo(t *testing.T) {
@@ -27,3 +40,389 @@ func TestMicro_Close(t *testing.T) {
 	m.Close()
 	m.Close()
 }
+
+func TestHealthHandler(t *testing.T) {
+	m := New()
+	m.AddHealthCheck("ok", func() error {
+		return nil
+	})
+
+	h := m.HealthHandler()
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("liveness should be ok, got %d", rw.Code)
+	}
+
+	// not started
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness should be unavailable before start, got %d", rw.Code)
+	}
+
+	m.(*micro).setState(stateReady)
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("readiness should be ok, got %d %s", rw.Code, rw.Body.String())
+	}
+
+	m.AddHealthCheck("db", func() error {
+		return errors.New("db down")
+	})
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readiness should be unavailable, got %d", rw.Code)
+	}
+	t.Log(rw.Body.String())
+
+	// slow check times out
+	timeout := HealthCheckTimeout
+	HealthCheckTimeout = 50 * time.Millisecond
+	defer func() { HealthCheckTimeout = timeout }()
+
+	m.(*micro).checkers = nil
+	m.AddHealthCheckContext("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	})
+	start := time.Now()
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rw.Code != http.StatusServiceUnavailable || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("slow check should time out, got %d %s", rw.Code, rw.Body.String())
+	}
+
+	m.Close()
+	if m.Ready() {
+		t.Fatal("should not be ready after close")
+	}
+}
+
+func TestMicro_ShutdownPhases(t *testing.T) {
+	m := New()
+	m.SetShutdownTimeout(time.Second)
+
+	mu := sync.Mutex{}
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	m.AddShutdownHook(PhaseCloseClients, "client", func(ctx context.Context) error {
+		record("client")
+		return nil
+	})
+
+	m.AddShutdownHook(PhaseStopTraffic, "traffic", func(ctx context.Context) error {
+		record("traffic")
+		return nil
+	})
+
+	m.AddShutdownHook(PhaseDrainServers, "hung", func(ctx context.Context) error {
+		record("hung")
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	}, time.Millisecond*50)
+
+	start := time.Now()
+	m.Close()
+
+	if took := time.Since(start); took > time.Millisecond*500 {
+		t.Fatalf("hung hook should be left behind, took %s", took)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(order, ",") != "traffic,hung,client" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+}
+
+func TestMicro_FlushAfterDeadline(t *testing.T) {
+	m := New()
+	m.SetShutdownTimeout(50 * time.Millisecond)
+
+	// uses up overall deadline
+	m.AddShutdownHook(PhaseDrainServers, "slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 0)
+
+	flushed := false
+	m.AddShutdownHook(PhaseFlushLogs, "flush", func(ctx context.Context) error {
+		flushed = ctx.Err() == nil
+		return nil
+	})
+
+	m.Close()
+	if !flushed {
+		t.Fatal("flush phase should have its own deadline")
+	}
+}
+
+func TestMicro_Worker(t *testing.T) {
+	m := New()
+
+	var runs int32
+	m.AddWorker("flaky", WorkerFunc(func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			return errors.New("flaky")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}), WorkerConf{
+		Restart: true,
+		Backoff: time.Millisecond,
+	})
+
+	m.AddWorker("broken", WorkerFunc(func(ctx context.Context) error {
+		for atomic.LoadInt32(&runs) < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		return errors.New("broken")
+	}))
+
+	// err of broken worker stops micro
+	m.Start()
+
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Fatalf("flaky worker should run 3 times, got %d", n)
+	}
+}
+
+func TestMicro_Reload(t *testing.T) {
+	defer log.SetGlobalLevel(log.DebugLevel)
+
+	f, err := ioutil.TempFile("", "micro_env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("# comment\nlog_level=\"warn\"\n")
+	f.Close()
+
+	EnvFile = f.Name()
+	defer func() {
+		EnvFile = ""
+		os.Unsetenv("log_level")
+		log.SetGlobalLevel(log.DebugLevel)
+	}()
+
+	m := New()
+	defer m.Close()
+
+	reloaded := false
+	m.OnReload(func() error {
+		reloaded = true
+		return nil
+	})
+
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reloaded {
+		t.Fatal("reload hook should be called")
+	}
+
+	if log.DebugEnabled() {
+		t.Fatal("log level should be warn after reload")
+	}
+}
+
+func TestMicro_ProdMode(t *testing.T) {
+	os.Setenv("mode", "prod")
+	defer func() {
+		os.Unsetenv("mode")
+		mode = ""
+		errs.ProdMode = false
+	}()
+
+	m := New()
+	defer m.Close()
+
+	if !errs.ProdMode {
+		t.Fatal("errs.ProdMode should be set by conf mode")
+	}
+}
+
+func TestMicro_BadConf(t *testing.T) {
+	os.Setenv("log_std_disable", "yes")
+	os.Setenv("log_level", "warn")
+	defer func() {
+		os.Unsetenv("log_std_disable")
+		os.Unsetenv("log_level")
+		log.SetGlobalLevel(log.DebugLevel)
+	}()
+
+	m := New()
+	defer m.Close()
+
+	if conf := m.Conf(); conf.LogLevel != "warn" || conf.LogStdDisable {
+		t.Fatalf("bad values should be skipped: %+v", conf)
+	}
+}
+
+// child process of TestMicro_InheritListener
+func inheritChild() {
+	ln := takeInherited("127.0.0.1:0")
+	if ln == nil {
+		os.Exit(2)
+	}
+	closeInherited()
+	notifyReady()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(3)
+	}
+	conn.Write([]byte("child"))
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestMicro_InheritListener(t *testing.T) {
+	if os.Getenv("micro_test_child") == "1" {
+		inheritChild()
+		return
+	}
+
+	m := New().(*micro)
+	ln, err := m.createListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, ready, err := m.spawn(os.Args[0], []string{"-test.run=^TestMicro_InheritListener$"}, append(os.Environ(), "micro_test_child=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+
+	if err := waitReady(ready, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// parent stops, conn should go to child
+	addr := ln.Addr().String()
+	m.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	data, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "child" {
+		t.Fatalf("conn should be accepted by child, got %q", data)
+	}
+
+	state, err := p.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !state.Success() {
+		t.Fatalf("child exit with %s", state)
+	}
+}
+
+func TestMicro_UpgradeNotReady(t *testing.T) {
+	m := New().(*micro)
+	defer m.Close()
+
+	if _, err := m.createListener("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// child runs no test and exits without notifying
+	p, ready, err := m.spawn(os.Args[0], []string{"-test.run=^$"}, os.Environ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+
+	if err := waitReady(ready, 10*time.Second); err == nil {
+		t.Fatal("waitReady should fail if child exits before ready")
+	}
+	p.Wait()
+}
+
+func TestMicro_Metrics(t *testing.T) {
+	m := New().(*micro)
+	defer m.Close()
+
+	rw := httptest.NewRecorder()
+	m.adminHandler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), "build_info{") {
+		t.Fatalf("metrics should contain build_info, got %d", rw.Code)
+	}
+}
+
+func TestMicro_AdminDebug(t *testing.T) {
+	defer log.SetGlobalLevel(log.DebugLevel)
+
+	m := New().(*micro)
+	defer m.Close()
+
+	h := m.adminHandler()
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/version", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("debug should be enabled outside prod, got %d", rw.Code)
+	}
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader("warn")))
+	if rw.Code != http.StatusOK || rw.Body.String() != "warn" {
+		t.Fatalf("log level should be changed, got %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPut, "/debug/loglevel?name=my*&level=debug", nil))
+	if rw.Code != http.StatusOK || rw.Body.String() != "debug" || log.NamedLevel("mysql") != log.DebugLevel {
+		t.Fatalf("named log level should be changed, got %d %s", rw.Code, rw.Body.String())
+	}
+	log.UnsetLevel("my*")
+
+	mode = "prod"
+	defer func() {
+		mode = ""
+	}()
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/version", nil))
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("debug should be forbidden without token in prod, got %d", rw.Code)
+	}
+
+	m.conf.AdminToken = "secret"
+	r := httptest.NewRequest(http.MethodGet, "/debug/version", nil)
+	r.Header.Set(AdminTokenHeader, "secret")
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("debug should be allowed with token in prod, got %d", rw.Code)
+	}
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("health should not require token, got %d", rw.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package micro
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// ReloadSignal notify signal to reload config instead of stopping.
+var ReloadSignal = []os.Signal{syscall.SIGHUP}
+
+// EnvFile is optional env file re-read on reload, KEY=VALUE per line.
+var EnvFile = os.Getenv("env_file")
+
+// OnReload add hook called on reload.
+func (m *micro) OnReload(f func() error) {
+	if f == nil {
+		return
+	}
+	m.mu.Lock()
+	m.reloadHooks = append(m.reloadHooks, f)
+	m.mu.Unlock()
+}
+
+// Reload re-read env file and conf, resets log level, then call reload hooks.
+func (m *micro) Reload() error {
+	if EnvFile != "" {
+		if err := loadEnvFile(EnvFile); err != nil {
+			return err
+		}
+	}
+
+	conf, err := loadConf()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.conf = conf
+	m.mu.Unlock()
+
+	log.SetGlobalLevel(getLogLevel(conf.LogLevel))
+	setLogRedact(conf)
+
+	if m.rdsWriter != nil {
+		m.rdsWriter.SetLevel(getLogLevel(conf.LogRdsLevel))
+	}
+
+	if m.fileWriter != nil {
+		m.fileWriter.SetLevel(getLogLevel(conf.LogFileLevel))
+	}
+
+	if m.netWriter != nil {
+		m.netWriter.SetLevel(getLogLevel(conf.LogNetLevel))
+	}
+
+	if m.syslogWriter != nil {
+		m.syslogWriter.SetLevel(getLogLevel(conf.LogSyslogLevel))
+	}
+
+	m.mu.Lock()
+	hooks := make([]func() error, len(m.reloadHooks))
+	copy(hooks, m.reloadHooks)
+	m.mu.Unlock()
+
+	var failed []string
+	for i := range hooks {
+		if err := hooks[i](); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("reload hooks err: %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// loadEnvFile set env from file, empty lines and lines start with # are ignored.
+func loadEnvFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.Index(line, "=")
+		if i == -1 {
+			continue
+		}
+
+		k := strings.TrimSpace(line[:i])
+		v := strings.Trim(strings.TrimSpace(line[i+1:]), `"'`)
+
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// levelWriter is a writer wrapper whose level can be changed at runtime.
+type levelWriter struct {
+	lv int32
+	w  io.Writer
+}
+
+func newLevelWriter(lv log.Level, w io.Writer) *levelWriter {
+	return &levelWriter{
+		lv: int32(lv),
+		w:  w,
+	}
+}
+
+// SetLevel change min level.
+func (w *levelWriter) SetLevel(lv log.Level) {
+	atomic.StoreInt32(&w.lv, int32(lv))
+}
+
+// Write write data to writer
+func (w *levelWriter) Write(p []byte) (n int, err error) {
+	return w.w.Write(p)
+}
+
+// WriteLevel write data to writer with level info provided
+func (w *levelWriter) WriteLevel(level log.Level, p []byte) (n int, err error) {
+	if level < log.Level(atomic.LoadInt32(&w.lv)) {
+		return len(p), nil
+	}
+
+	if lw, ok := w.w.(interface {
+		WriteLevel(log.Level, []byte) (int, error)
+	}); ok {
+		return lw.WriteLevel(level, p)
+	}
+
+	return w.w.Write(p)
+}
@@ -0,0 +1,200 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// Phase is shutdown phase, Close runs hooks phase by phase, hooks in same phase run FILO.
+type Phase int
+
+// shutdown phases
+const (
+	PhaseStopTraffic  Phase = iota // stop accepting traffic, e.g. deregister
+	PhaseDrainServers              // drain gRPC and http servers, workers
+	PhaseCloseClients              // close db, redis, mq clients
+	PhaseFlushLogs                 // flush async logs
+
+	phaseCount
+)
+
+var phaseNames = [phaseCount]string{"stop_traffic", "drain_servers", "close_clients", "flush_logs"}
+
+func (p Phase) String() string {
+	if p < 0 || p >= phaseCount {
+		return "phase(" + fmt.Sprint(int(p)) + ")"
+	}
+	return phaseNames[p]
+}
+
+var (
+	// ShutdownTimeout is default overall deadline of Close.
+	ShutdownTimeout = 60 * time.Second
+
+	// HookTimeout is default timeout of each shutdown hook.
+	HookTimeout = 30 * time.Second
+
+	// FlushTimeout is deadline of PhaseFlushLogs, it doesn't count in ShutdownTimeout,
+	// so logs are flushed even if earlier phases overran.
+	FlushTimeout = 10 * time.Second
+)
+
+type shutdownHook struct {
+	name    string
+	timeout time.Duration
+	f       func(ctx context.Context) error
+}
+
+// AddShutdownHook add named hook to phase, optional timeout overwrites HookTimeout.
+// ctx passed to f is done when hook overruns, f should force-stop then.
+func (m *micro) AddShutdownHook(phase Phase, name string, f func(ctx context.Context) error, timeout ...time.Duration) {
+	if f == nil {
+		return
+	}
+
+	if phase < 0 || phase >= phaseCount {
+		phase = PhaseCloseClients
+	}
+
+	h := shutdownHook{
+		name:    name,
+		timeout: HookTimeout,
+		f:       f,
+	}
+
+	if len(timeout) != 0 {
+		h.timeout = timeout[0]
+	}
+
+	m.mu.Lock()
+	m.hooks[phase] = append(m.hooks[phase], h)
+	m.mu.Unlock()
+}
+
+// SetShutdownTimeout set overall deadline of Close.
+func (m *micro) SetShutdownTimeout(d time.Duration) {
+	m.mu.Lock()
+	m.shutdownTimeout = d
+	m.mu.Unlock()
+}
+
+// Close runs all shutdown hooks phase by phase, hooks overrun are left behind.
+func (m *micro) Close() {
+	m.setState(stateStopping)
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.hooks = [phaseCount][]shutdownHook{}
+	timeout := m.shutdownTimeout
+	m.mu.Unlock()
+
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var total int
+	var failed, timedOut []string
+
+	for p := Phase(0); p < phaseCount; p++ {
+		phaseCtx := ctx
+		if p == PhaseFlushLogs {
+			if total != 0 {
+				// logs after flush may be lost, so summary goes first
+				logShutdownSummary(time.Since(start), total, failed, timedOut)
+			}
+
+			var cancelFlush context.CancelFunc
+			phaseCtx, cancelFlush = context.WithTimeout(context.Background(), FlushTimeout)
+			defer cancelFlush()
+		}
+
+		for i := len(hooks[p]) - 1; i >= 0; i-- {
+			h := hooks[p][i]
+			total++
+
+			err := runHook(phaseCtx, h)
+			if err == nil {
+				continue
+			}
+
+			name := p.String() + "/" + h.name
+			if p == PhaseFlushLogs {
+				// logger may be closed, report to stderr
+				fmt.Fprintf(os.Stderr, "micro close resource %s err: %s\n", name, err)
+				continue
+			}
+
+			if err == context.DeadlineExceeded {
+				timedOut = append(timedOut, name)
+				log.Errorf("close resource %s timeout", name)
+			} else {
+				failed = append(failed, name)
+				log.Errorf("close resource %s err: %s", name, err.Error())
+			}
+		}
+	}
+}
+
+func logShutdownSummary(took time.Duration, total int, failed, timedOut []string) {
+	if len(failed) == 0 && len(timedOut) == 0 {
+		log.Infof("micro shutdown %d hooks took %s", total, took)
+		return
+	}
+
+	log.Errorf("micro shutdown %d hooks took %s, failed: [%s], timeout: [%s]", total, took,
+		strings.Join(failed, ", "), strings.Join(timedOut, ", "))
+}
+
+// runHook runs h, returns context.DeadlineExceeded if h overruns.
+func runHook(ctx context.Context, h shutdownHook) error {
+	var cancel context.CancelFunc
+	if h.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- h.f(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// check again, hook may finish at the same time
+		select {
+		case err := <-done:
+			return err
+		default:
+			return context.DeadlineExceeded
+		}
+	}
+}
+
+// funcName returns name of f for summary.
+func funcName(f interface{}) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		name := fn.Name()
+		if i := strings.LastIndex(name, "/"); i != -1 {
+			name = name[i+1:]
+		}
+		return name
+	}
+	return "unknown"
+}
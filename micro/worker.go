@@ -0,0 +1,176 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// Worker is background job managed by micro, e.g. mq consumer, cron loop.
+// Run should return when ctx is done.
+type Worker interface {
+	Run(ctx context.Context) error
+}
+
+// WorkerFunc is helper type to use func as Worker.
+type WorkerFunc func(ctx context.Context) error
+
+// Run calls f(ctx).
+func (f WorkerFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// WorkerConf is conf for worker.
+type WorkerConf struct {
+	OnStart     func() error  // called before each Run
+	OnStop      func() error  // called after each Run
+	Restart     bool          // restart worker if Run returns err
+	MaxRestarts int           // 0 means unlimited
+	Backoff     time.Duration // first restart backoff, doubled each time, default 1s
+	MaxBackoff  time.Duration // default 1min
+}
+
+const (
+	defaultWorkerBackoff    = time.Second
+	defaultWorkerMaxBackoff = time.Minute
+)
+
+// AddWorker add background worker, it starts with Start and ctx is cancelled on shutdown.
+// Worker err stops micro unless conf.Restart is set.
+func (m *micro) AddWorker(name string, w Worker, conf ...WorkerConf) {
+	if w == nil {
+		return
+	}
+
+	c := WorkerConf{}
+	if len(conf) != 0 {
+		c = conf[0]
+	}
+
+	if c.Backoff <= 0 {
+		c.Backoff = defaultWorkerBackoff
+	}
+
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultWorkerMaxBackoff
+	}
+
+	m.mu.Lock()
+	m.workers = append(m.workers, func() {
+		m.runWorker(name, w, c)
+	})
+	m.mu.Unlock()
+}
+
+// OnStart add hook called by Start before serving, Start aborts if it returns err.
+func (m *micro) OnStart(f func() error) {
+	if f == nil {
+		return
+	}
+	m.mu.Lock()
+	m.startHooks = append(m.startHooks, f)
+	m.mu.Unlock()
+}
+
+// OnStop add hook called on shutdown in PhaseStopTraffic.
+func (m *micro) OnStop(f func() error) {
+	if f == nil {
+		return
+	}
+	m.AddShutdownHook(PhaseStopTraffic, funcName(f), func(context.Context) error {
+		return f()
+	})
+}
+
+// stopWorkers cancels workers ctx and waits for them.
+func (m *micro) stopWorkers(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *micro) runWorker(name string, w Worker, conf WorkerConf) {
+	defer m.wg.Done()
+
+	backoff := conf.Backoff
+
+	for restarts := 0; ; restarts++ {
+		err := m.runWorkerOnce(w, conf)
+
+		// shutting down
+		if m.ctx.Err() != nil {
+			if err != nil && err != context.Canceled {
+				log.Errorf("worker %s stop err: %s", name, err)
+			}
+			return
+		}
+
+		if err == nil {
+			log.Infof("worker %s exit", name)
+			return
+		}
+
+		if !conf.Restart || (conf.MaxRestarts > 0 && restarts >= conf.MaxRestarts) {
+			m.fail(fmt.Errorf("worker %s err: %s", name, err))
+			return
+		}
+
+		log.Errorf("worker %s err: %s, restart in %s", name, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-m.ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > conf.MaxBackoff {
+			backoff = conf.MaxBackoff
+		}
+	}
+}
+
+func (m *micro) runWorkerOnce(w Worker, conf WorkerConf) (err error) {
+	if conf.OnStart != nil {
+		if err = conf.OnStart(); err != nil {
+			return err
+		}
+	}
+
+	if conf.OnStop != nil {
+		defer func() {
+			if e := conf.OnStop(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, log.TakeStacktrace())
+		}
+	}()
+
+	return w.Run(m.ctx)
+}
+
+// fail notify Start to stop, only first err is kept.
+func (m *micro) fail(err error) {
+	select {
+	case m.errChan <- err:
+	default:
+	}
+}
@@ -23,15 +23,11 @@ func (h *Hook) After(ctx context.Context, query string, args ...interface{}) (co
 		return ctx, nil
 	}
 
-	logger := log.KVPair(map[string]interface{}{
+	logger := log.Ctx(ctx).Named("mysql").KVPair(map[string]interface{}{
 		"span": driverName,
 		"took": nanoToMs(time.Since(startAt).Nanoseconds()),
 	})
 
-	if tid, ok := ctx.Value("x-request-id").(string); ok {
-		logger = logger.Trace(tid)
-	}
-
 	if logger.DebugEnabled() {
 		logger.Debugf("> %s. %v", query, args)
 	}
@@ -8,6 +8,9 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/arcplus/go-lib/errs"
+	"github.com/arcplus/go-lib/internal/sqli"
+	"github.com/arcplus/go-lib/metrics"
 	"github.com/arcplus/go-lib/safemap"
 )
 
@@ -15,6 +18,16 @@ const driverName = "mysql"
 
 func init() {
 	sql.Register(driverName+HookSuffix, sqlhooks.Wrap(&mysql.MySQLDriver{}, &Hook{}))
+
+	metrics.MustRegister(sqli.NewStatsCollector(driverName, func() map[string]*sqlx.DB {
+		items := store.Items()
+
+		dbs := make(map[string]*sqlx.DB, len(items))
+		for k, v := range items {
+			dbs[k.(string)] = v.(*sqlx.DB)
+		}
+		return dbs
+	}))
 }
 
 // alias
@@ -54,21 +67,17 @@ func DB(name string) *sqlx.DB {
 
 // HealthCheck ping db
 func HealthCheck() error {
-	errs := make(map[string]error)
+	var es []error
 
 	clients := store.Items()
 
 	for k, v := range clients {
 		if err := v.(*sqlx.DB).Ping(); err != nil {
-			errs[k.(string)] = err
+			es = append(es, errs.Annotate(err, "mysql '%s' ping: %s", k, err))
 		}
 	}
 
-	if len(errs) != 0 {
-		return fmt.Errorf("%v", errs)
-	}
-
-	return nil
+	return errs.Combine(es...)
 }
 
 // Close closes all mysql conn
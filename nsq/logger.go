@@ -16,7 +16,7 @@ func (logger) Output(calldepth int, s string) error {
 	}
 
 	// TODO depth + caller
-	l := log.Logger().KV("span", "nsq").Skip(calldepth)
+	l := log.Named("nsq").KV("span", "nsq").Skip(calldepth)
 
 	if strings.HasPrefix(s, "INF") {
 		l.Info(s[5:])
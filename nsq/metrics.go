@@ -0,0 +1,33 @@
+package nsq
+
+import (
+	"github.com/arcplus/go-lib/metrics"
+)
+
+var (
+	published = metrics.NewCounterVec("nsq_published_total",
+		"Total number of messages published.", "topic", "result")
+
+	consumed = metrics.NewCounterVec("nsq_consumed_total",
+		"Total number of messages consumed.", "topic", "channel", "result")
+)
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// metricsHandler records consumed count of handler.
+type metricsHandler struct {
+	topic   string
+	channel string
+	h       Handler
+}
+
+func (m *metricsHandler) HandleMessage(msg *Message) error {
+	err := m.h.HandleMessage(msg)
+	consumed.WithLabelValues(m.topic, m.channel, result(err)).Inc()
+	return err
+}
@@ -49,7 +49,9 @@ func Publish(topic string, body []byte) error {
 		return err
 	}
 
-	return pubMgr.Publish(topic, body)
+	err = pubMgr.Publish(topic, body)
+	published.WithLabelValues(topic, result(err)).Inc()
+	return err
 }
 
 func PublishWithJsonExt(topic string, body []byte, ext *MsgExt) error {
@@ -59,6 +61,7 @@ func PublishWithJsonExt(topic string, body []byte, ext *MsgExt) error {
 	}
 
 	_, _, _, err = pubMgr.PublishWithJsonExt(topic, body, ext)
+	published.WithLabelValues(topic, result(err)).Inc()
 	return err
 }
 
@@ -69,6 +72,7 @@ func PublishOrdered(topic string, partitionKey []byte, body []byte) error {
 	}
 
 	_, _, _, err = pubMgr.PublishOrdered(topic, partitionKey, body)
+	published.WithLabelValues(topic, result(err)).Inc()
 	return err
 }
 
@@ -79,5 +83,6 @@ func PublishOrderedWithJsonExt(topic string, partitionKey []byte, body []byte, e
 	}
 
 	_, _, _, err = pubMgr.PublishOrderedWithJsonExt(topic, partitionKey, body, ext)
+	published.WithLabelValues(topic, result(err)).Inc()
 	return err
 }
@@ -31,7 +31,11 @@ func SubscribeHandler(lupdAddr string, config *Config, topic, channel string, ha
 
 	consumer.SetLogger(logger{}, nsq.LogLevelInfo)
 
-	consumer.AddConcurrentHandlers(handler, concurrency)
+	consumer.AddConcurrentHandlers(&metricsHandler{
+		topic:   topic,
+		channel: channel,
+		h:       handler,
+	}, concurrency)
 
 	return consumer.ConnectToNSQLookupd(getLupdAddr(lupdAddr))
 }
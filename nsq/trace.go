@@ -0,0 +1,61 @@
+package nsq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/youzan/go-nsq"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// TraceKey is key of trace id in json ext.
+const TraceKey = "tid"
+
+// ContextHandlerFunc is handler with ctx carrying logger of message trace id.
+type ContextHandlerFunc func(ctx context.Context, msg *Message) error
+
+// SubscribeContextHandleFunc subscribe with ContextHandlerFunc, trace id in msg ext is
+// restored, logger can be got by log.Ctx(ctx).
+func SubscribeContextHandleFunc(addr string, config *Config, topic, channel string, handleFunc ContextHandlerFunc, concurrency int) error {
+	return SubscribeHandler(addr, config, topic, channel, nsq.HandlerFunc(func(msg *Message) error {
+		return handleFunc(MsgContext(msg), msg)
+	}), concurrency)
+}
+
+// MsgContext returns ctx with logger of trace id and traceparent in msg json ext,
+// missing ones are generated.
+func MsgContext(msg *Message) context.Context {
+	var tid, traceparent string
+
+	if len(msg.ExtBytes) != 0 {
+		ext := map[string]interface{}{}
+		if json.Unmarshal(msg.ExtBytes, &ext) == nil {
+			tid, _ = ext[TraceKey].(string)
+			traceparent, _ = ext[log.TraceParentKey].(string)
+		}
+	}
+
+	return log.WithContext(context.Background(), log.FromRequest(tid, traceparent))
+}
+
+// PublishWithCtx publish with trace id and traceparent of logger in ctx in json ext.
+func PublishWithCtx(ctx context.Context, topic string, body []byte) error {
+	l := log.Ctx(ctx)
+
+	custom := map[string]string{}
+	if tid := l.TraceID(); tid != "" {
+		custom[TraceKey] = tid
+	}
+	if tp := l.TraceParent(); tp.IsValid() {
+		custom[log.TraceParentKey] = tp.Child().String()
+	}
+
+	if len(custom) == 0 {
+		return Publish(topic, body)
+	}
+
+	return PublishWithJsonExt(topic, body, &MsgExt{
+		Custom: custom,
+	})
+}
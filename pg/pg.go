@@ -9,13 +9,26 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 
+	"github.com/arcplus/go-lib/errs"
 	"github.com/arcplus/go-lib/internal/sqli"
+	"github.com/arcplus/go-lib/metrics"
 )
 
 const driverName = "postgres"
 
 func init() {
 	sql.Register(driverName+sqli.HookSuffix, sqlhooks.Wrap(&pq.Driver{}, &sqli.Hook{}))
+
+	metrics.MustRegister(sqli.NewStatsCollector(driverName, func() map[string]*sqlx.DB {
+		pool.RLock()
+		defer pool.RUnlock()
+
+		dbs := make(map[string]*sqlx.DB, len(pool.clients))
+		for name, db := range pool.clients {
+			dbs[name] = db
+		}
+		return dbs
+	}))
 }
 
 var pool = &struct {
@@ -57,40 +70,34 @@ func DB(name string) *sqlx.DB {
 
 // HealthCheck ping db
 func HealthCheck() error {
-	errs := make(map[string]error)
+	var es []error
 
 	pool.RLock()
 
 	for name, db := range pool.clients {
 		if err := db.Ping(); err != nil {
-			errs[name] = err
+			es = append(es, errs.Annotate(err, "pg '%s' ping: %s", name, err))
 		}
 	}
 
 	pool.RUnlock()
 
-	if len(errs) != 0 {
-		return fmt.Errorf("%v", errs)
-	}
-	return nil
+	return errs.Combine(es...)
 }
 
 // Close closes all mysql conn
 func Close() error {
-	errs := make(map[string]error)
+	var es []error
 
 	pool.Lock()
 
 	for name, db := range pool.clients {
 		if err := db.Close(); err != nil {
-			errs[name] = err
+			es = append(es, errs.Annotate(err, "pg '%s' close: %s", name, err))
 		}
 	}
 
 	pool.Unlock()
 
-	if len(errs) != 0 {
-		return fmt.Errorf("%v", errs)
-	}
-	return nil
+	return errs.Combine(es...)
 }
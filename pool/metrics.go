@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"github.com/arcplus/go-lib/metrics"
+)
+
+// poolCollector collects active and idle count of ClientPool.
+type poolCollector struct {
+	p      *ClientPool
+	active *metrics.Desc
+	idle   *metrics.Desc
+}
+
+// Collector returns metrics collector of p, name is used as label.
+//
+// For example:
+//
+//	metrics.MustRegister(p.Collector("hbase"))
+func (p *ClientPool) Collector(name string) metrics.Collector {
+	constLabels := metrics.Labels{"name": name}
+	return &poolCollector{
+		p:      p,
+		active: metrics.NewDesc("client_pool_active_connections", "The number of active connections in the pool.", nil, constLabels),
+		idle:   metrics.NewDesc("client_pool_idle_connections", "The number of idle connections in the pool.", nil, constLabels),
+	}
+}
+
+// Describe implements metrics.Collector
+func (c *poolCollector) Describe(ch chan<- *metrics.Desc) {
+	ch <- c.active
+	ch <- c.idle
+}
+
+// Collect implements metrics.Collector
+func (c *poolCollector) Collect(ch chan<- metrics.Metric) {
+	ch <- metrics.MustNewGauge(c.active, float64(c.p.ActiveCount()))
+	ch <- metrics.MustNewGauge(c.idle, float64(c.p.IdleCount()))
+}
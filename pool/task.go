@@ -2,8 +2,11 @@ package pool
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
+
+	"github.com/arcplus/go-lib/errs"
 )
 
 const (
@@ -37,13 +40,15 @@ type WorkFunc func()
 // WorkFuncWithCtx is simple work func
 type WorkFuncWithCtx func(ctx context.Context) error
 
+// MultiRun runs fs concurrently, a single error is returned unchanged, more are returned
+// as *errs.MultiError in order of occurrence.
 func MultiRun(fs ...func() error) error {
 	if len(fs) == 0 {
 		return nil
 	}
 
-	var err error
-	var errOnce = &sync.Once{}
+	var mu sync.Mutex
+	var es []error
 
 	wg := &sync.WaitGroup{}
 	for i := range fs {
@@ -53,19 +58,21 @@ func MultiRun(fs ...func() error) error {
 		wg.Add(1)
 		go func(i int) {
 			if e := fs[i](); e != nil {
-				errOnce.Do(func() {
-					err = e
-				})
+				mu.Lock()
+				es = append(es, e)
+				mu.Unlock()
 			}
 			wg.Done()
 		}(i)
 	}
 
 	wg.Wait()
-	return err
+	return errs.Combine(es...)
 }
 
-// MultiRunWithCtx with ctx notify
+// MultiRunWithCtx with ctx notify, ctx is canceled on the first error, a single error
+// is returned unchanged, more are returned as *errs.MultiError in order of occurrence,
+// context.Canceled returned after the first error is skipped.
 func MultiRunWithCtx(ctx context.Context, fs ...WorkFuncWithCtx) error {
 	if len(fs) == 0 {
 		return nil
@@ -78,8 +85,8 @@ func MultiRunWithCtx(ctx context.Context, fs ...WorkFuncWithCtx) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	var err error
-	var errOnce = &sync.Once{}
+	var mu sync.Mutex
+	var es []error
 
 	wg := &sync.WaitGroup{}
 	for i := range fs {
@@ -89,17 +96,20 @@ func MultiRunWithCtx(ctx context.Context, fs ...WorkFuncWithCtx) error {
 		wg.Add(1)
 		go func(i int) {
 			if e := fs[i](ctx); e != nil {
-				errOnce.Do(func() {
-					err = e
-					cancel()
-				})
+				mu.Lock()
+				// ctx err of workers stopped by an earlier error is not kept
+				if len(es) == 0 || !errors.Is(e, context.Canceled) {
+					es = append(es, e)
+				}
+				mu.Unlock()
+				cancel()
 			}
 			wg.Done()
 		}(i)
 	}
 
 	wg.Wait()
-	return err
+	return errs.Combine(es...)
 }
 
 // MultiRunWithPool
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/arcplus/go-lib/errs"
 )
 
 func TestMultiRun(t *testing.T) {
@@ -29,8 +31,20 @@ func TestMultiRun(t *testing.T) {
 	}
 
 	err := MultiRun(w1, w2, w3)
-	if err != w3e {
-		t.Fatal("multi be w3e", err)
+	me, ok := err.(*errs.MultiError)
+	if !ok || len(me.Errors()) != 2 || me.Errors()[0] != w3e {
+		t.Fatal("multi be w3e and w2 err", err)
+	}
+	if !errs.IsErr(err, w3e) {
+		t.Fatal("err should contain w3e")
+	}
+
+	if err := MultiRun(func() error { return nil }); err != nil {
+		t.Fatal("err should be nil", err)
+	}
+
+	if err := MultiRun(w1, w3); err != w3e {
+		t.Fatal("single err should be returned unchanged", err)
 	}
 }
 
@@ -60,9 +74,15 @@ func TestMultiRunWithCtx(t *testing.T) {
 		fmt.Println("second", ctx.Err())
 		return nil
 	}
-	err := MultiRunWithCtx(ctx, w1, w2, w3)
-	if err != w2e {
-		t.Fatal("result should be w2e")
+	// stopped by w2 error, its ctx err is skipped
+	w4 := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	err := MultiRunWithCtx(ctx, w1, w2, w3, w4)
+	me, ok := err.(*errs.MultiError)
+	if !ok || len(me.Errors()) != 2 || me.Errors()[0] != w2e {
+		t.Fatal("result should be w2e and w1 err", err)
 	}
 }
 
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"github.com/go-redis/redis"
+
+	"github.com/arcplus/go-lib/metrics"
+)
+
+func init() {
+	metrics.MustRegister(newStatsCollector())
+}
+
+// statsCollector collects pool stats of registered clients.
+type statsCollector struct {
+	hits     *metrics.Desc
+	misses   *metrics.Desc
+	timeouts *metrics.Desc
+	total    *metrics.Desc
+	idle     *metrics.Desc
+}
+
+func newStatsCollector() *statsCollector {
+	labels := []string{"name"}
+	return &statsCollector{
+		hits:     metrics.NewDesc("redis_pool_hits_total", "Number of times free connection was found in the pool.", labels, nil),
+		misses:   metrics.NewDesc("redis_pool_misses_total", "Number of times free connection was not found in the pool.", labels, nil),
+		timeouts: metrics.NewDesc("redis_pool_timeouts_total", "Number of times a wait timeout occurred.", labels, nil),
+		total:    metrics.NewDesc("redis_pool_connections", "Number of total connections in the pool.", labels, nil),
+		idle:     metrics.NewDesc("redis_pool_idle_connections", "Number of idle connections in the pool.", labels, nil),
+	}
+}
+
+// Describe implements metrics.Collector
+func (c *statsCollector) Describe(ch chan<- *metrics.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.total
+	ch <- c.idle
+}
+
+// Collect implements metrics.Collector
+func (c *statsCollector) Collect(ch chan<- metrics.Metric) {
+	for k, v := range redisStore.Items() {
+		name := k.(string)
+		s := v.(*redis.Client).PoolStats()
+		ch <- metrics.MustNewCounter(c.hits, float64(s.Hits), name)
+		ch <- metrics.MustNewCounter(c.misses, float64(s.Misses), name)
+		ch <- metrics.MustNewCounter(c.timeouts, float64(s.Timeouts), name)
+		ch <- metrics.MustNewGauge(c.total, float64(s.TotalConns), name)
+		ch <- metrics.MustNewGauge(c.idle, float64(s.IdleConns), name)
+	}
+}
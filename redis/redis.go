@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-redis/redis"
 
+	"github.com/arcplus/go-lib/errs"
 	"github.com/arcplus/go-lib/json"
 	"github.com/arcplus/go-lib/safemap"
 )
@@ -59,22 +60,18 @@ func Get(name string) *redis.Client {
 
 // HealthCheck ping rds
 func HealthCheck() error {
-	errs := make(map[string]error)
+	var es []error
 
 	redisClients := redisStore.Items()
 
 	for k, v := range redisClients {
 		err := v.(*redis.Client).Ping().Err()
 		if err != nil {
-			errs[k.(string)] = err
+			es = append(es, errs.Annotate(err, "redis '%s' ping: %s", k, err))
 		}
 	}
 
-	if len(errs) != 0 {
-		return fmt.Errorf("%v", errs)
-	}
-
-	return nil
+	return errs.Combine(es...)
 }
 
 // Close close all redis conn
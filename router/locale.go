@@ -0,0 +1,20 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/arcplus/go-lib/errs"
+)
+
+// Locale matches Accept-Language header with locales of errs catalogs, the locale can be
+// got by errs.LocaleFromContext(r.Context()) and errs.Localize(r.Context(), err) localizes
+// alert of err in following handlers.
+func Locale() HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if locale := errs.MatchLocale(r.Header.Get("Accept-Language")); locale != "" {
+			r = r.WithContext(errs.WithLocale(r.Context(), locale))
+		}
+
+		next(rw, r)
+	}
+}
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/urfave/negroni"
+
+	"github.com/arcplus/go-lib/metrics"
+)
+
+var (
+	httpHandled = metrics.NewCounterVec("http_server_requests_total",
+		"Total number of http requests completed on the server.", "method", "path", "code")
+
+	httpHandling = metrics.NewHistogramVec("http_server_handling_seconds",
+		"Histogram of response latency (seconds) of http requests that had been handled by the server.", nil, "method", "path")
+)
+
+// metricsHandler records count and latency of route, path is route pattern to limit cardinality.
+func metricsHandler(method, path string) HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		start := time.Now()
+
+		next(rw, r)
+
+		code := http.StatusOK
+		if nrw, ok := rw.(negroni.ResponseWriter); ok && nrw.Status() != 0 {
+			code = nrw.Status()
+		}
+
+		httpHandled.WithLabelValues(method, path, strconv.Itoa(code)).Inc()
+		httpHandling.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+	}
+}
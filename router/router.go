@@ -73,13 +73,17 @@ func (r *Router) UseFunc(handlers ...HandlerFunc) {
 func (r *Router) Handler(method, path string, handlers ...HandlerFunc) {
 	n := negroni.New()
 
+	path = r.joinPath(path)
+
+	n.UseFunc(metricsHandler(method, path))
+
 	handlers = append(r.handlers, handlers...)
 
 	for i := range handlers {
 		n.UseFunc(handlers[i])
 	}
 
-	r.router.Handler(method, r.joinPath(path), n)
+	r.router.Handler(method, path, n)
 }
 
 // POST http post method
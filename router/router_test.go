@@ -1,10 +1,15 @@
 package router
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/arcplus/go-lib/errs"
+	"github.com/arcplus/go-lib/log"
 )
 
 func TestHandler(t *testing.T) {
@@ -236,3 +241,61 @@ func TestPanic(t *testing.T) {
 		t.Fatal("after should exist")
 	}
 }
+
+func TestTrace(t *testing.T) {
+	var tid string
+	var tp log.TraceParent
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		tid = r.Header.Get(RequestIDHeader)
+		tp, _ = log.ParseTraceParent(r.Header.Get(log.TraceParentKey))
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+
+	router := New(Trace())
+	router.GET("/", Wrap(func(rw http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req.WithContext(r.Context()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(log.TraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, r)
+
+	if rw.Header().Get(RequestIDHeader) != "4bf92f3577b34da6a3ce929d0e0e4736" || tid != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("bad request id %s %s", rw.Header().Get(RequestIDHeader), tid)
+	}
+
+	if tp.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tp.SpanID == "00f067aa0ba902b7" {
+		t.Fatalf("bad traceparent %+v", tp)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	if err := errs.AddCatalog("zh-CN", map[string]string{"1404": "资源不存在"}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := New(Locale())
+	router.GET("/", Wrap(func(rw http.ResponseWriter, r *http.Request) {
+		err := errs.Localize(r.Context(), errs.New(errs.CodeNotFound, "not found"))
+		rw.WriteHeader(errs.HTTPStatus(errs.CodeNotFound))
+		json.NewEncoder(rw).Encode(err)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr;q=0.9, zh;q=0.8, zh-CN")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, r)
+
+	if rw.Code != http.StatusNotFound || !strings.Contains(rw.Body.String(), `"alert":"资源不存在"`) {
+		t.Fatalf("bad response %d %s", rw.Code, rw.Body.String())
+	}
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/arcplus/go-lib/log"
+)
+
+// RequestIDHeader is http header of trace id.
+const RequestIDHeader = "X-Request-Id"
+
+// Trace reads trace id from RequestIDHeader and W3C traceparent header, missing ones are
+// generated, the logger with them can be got by log.Ctx(r.Context()) in following handlers.
+func Trace() HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		logger := log.FromRequest(r.Header.Get(RequestIDHeader), r.Header.Get(log.TraceParentKey))
+
+		rw.Header().Set(RequestIDHeader, logger.TraceID())
+
+		next(rw, r.WithContext(log.WithContext(r.Context(), logger)))
+	}
+}
+
+// Transport is http.RoundTripper propagates trace id and traceparent of logger in request
+// ctx to outbound request, traceparent of a new child span is sent.
+type Transport struct {
+	Base http.RoundTripper // default is http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	l := log.Ctx(r.Context())
+
+	tp := l.TraceParent()
+	if tp.IsValid() {
+		tp = tp.Child()
+	} else {
+		tp = log.NewTraceParent()
+	}
+
+	// RoundTripper should not modify request
+	r = r.Clone(r.Context())
+	r.Header.Set(log.TraceParentKey, tp.String())
+
+	if tid := l.TraceID(); tid != "" && r.Header.Get(RequestIDHeader) == "" {
+		r.Header.Set(RequestIDHeader, tid)
+	}
+
+	return base.RoundTrip(r)
+}
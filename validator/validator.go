@@ -6,56 +6,101 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/arcplus/go-lib/errs"
 )
 
 type Validator interface {
 	Validate() error
 }
 
-// Validate checks constraints
+// Validate checks constraints, it returns on the first failing constraint.
 func Validate(req interface{}, constraints ...string) error {
 	// using Validate method if exist
 	if v, ok := req.(Validator); ok {
 		return v.Validate()
 	}
 
-	if len(constraints) == 0 {
-		return nil
+	rv := reflect.Indirect(reflect.ValueOf(req))
+
+	for _, c := range constraints {
+		if c == "" {
+			continue
+		}
+
+		if v := check(rv, c); v != nil {
+			return fmt.Errorf("field '%s' failed with %s", v.Field, v.Message)
+		}
 	}
 
-	rv := reflect.Indirect(reflect.ValueOf(req))
+	return nil
+}
 
-	var err error
+// ValidateAll checks all constraints, failing ones are returned as *errs.Error
+// of errs.CodeBadRequest with field violations.
+func ValidateAll(req interface{}, constraints ...string) error {
+	// using Validate method if exist
+	if v, ok := req.(Validator); ok {
+		return v.Validate()
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(req))
 
+	var vs errs.Violations
 	for _, c := range constraints {
 		if c == "" {
 			continue
 		}
 
-		nodeName, funcName, funcParams := splitToken(c)
+		if v := check(rv, c); v != nil {
+			vs = append(vs, *v)
+		}
+	}
 
-		rv := rv
+	return vs.Err()
+}
 
-		for _, node := range strings.Split(nodeName, ".") {
-			rv, err = valueWalker(rv, node)
-			if err != nil {
-				return fmt.Errorf("field '%s' failed with %s", nodeName, err.Error())
-			}
+// check returns violation if c failed.
+func check(rv reflect.Value, c string) *errs.FieldViolation {
+	nodeName, funcName, funcParams := splitToken(c)
+
+	v := &errs.FieldViolation{
+		Field: nodeName,
+		Rule:  funcName,
+	}
+
+	if funcName == "" {
+		v.Rule = "required"
+	}
+
+	if funcParams != "" {
+		v.Params = map[string]string{"param": funcParams}
+	}
+
+	var err error
+	for _, node := range strings.Split(nodeName, ".") {
+		rv, err = valueWalker(rv, node)
+		if err != nil {
+			v.Message = err.Error()
+			return v
 		}
+	}
 
-		if funcName == "" {
-			if !normalValidate(rv) {
-				return fmt.Errorf("field '%s' failed with should not empty", nodeName)
+	if funcName == "" {
+		if !normalValidate(rv) {
+			v.Message = "should not empty"
+			return v
+		}
+	} else {
+		if f, ok := funcMap[funcName]; ok && f != nil {
+			err = f(rv, funcParams)
+			if err != nil {
+				v.Message = fmt.Sprintf("%s '%s'", err, c)
+				return v
 			}
 		} else {
-			if f, ok := funcMap[funcName]; ok && f != nil {
-				err = f(rv, funcParams)
-				if err != nil {
-					return fmt.Errorf("field '%s' failed with %s '%s'", nodeName, err, c)
-				}
-			} else {
-				return fmt.Errorf("field '%s' failed with func '%s' not exist", nodeName, funcName)
-			}
+			v.Message = fmt.Sprintf("func '%s' not exist", funcName)
+			return v
 		}
 	}
 
@@ -3,6 +3,8 @@ package validator
 import (
 	"errors"
 	"testing"
+
+	"github.com/arcplus/go-lib/errs"
 )
 
 func TestSplitToken(t *testing.T) {
@@ -357,3 +359,46 @@ func BenchmarkValidate(b *testing.B) {
 		Validate(v, "a.0.b")
 	}
 }
+
+func TestValidateAll(t *testing.T) {
+	type A struct {
+		Name   string
+		Age    int
+		Gender string
+		Tags   []string
+	}
+
+	a := A{
+		Age:    200,
+		Gender: "x",
+		Tags:   []string{"go"},
+	}
+
+	err := ValidateAll(a, "name", "age:range(0|150)", "gender:in(m|f)", "tags")
+	if !errs.IsCode(err, errs.CodeBadRequest) {
+		t.Fatal("should be bad request err", err)
+	}
+	t.Log(err)
+
+	vs := err.(*errs.Error).Violations()
+	if len(vs) != 3 {
+		t.Fatal("should be 3 violations", vs)
+	}
+
+	if vs[0].Field != "name" || vs[0].Rule != "required" {
+		t.Fatal(vs[0])
+	}
+
+	if vs[1].Field != "age" || vs[1].Rule != "range" || vs[1].Params["param"] != "0|150" {
+		t.Fatal(vs[1])
+	}
+
+	if vs[2].Field != "gender" || vs[2].Rule != "in" {
+		t.Fatal(vs[2])
+	}
+
+	a = A{Name: "elvizlai", Age: 18, Gender: "m"}
+	if err := ValidateAll(a, "name", "age:range(0|150)", "gender:in(m|f)"); err != nil {
+		t.Fatal(err)
+	}
+}